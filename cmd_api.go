@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	"github.com/gciavarrini/cf-application-discovery/pkg/output"
+)
+
+func runAPI(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("api", stderr)
+	endpoint := fs.String("api", "", "URL of the Cloud Foundry API, e.g. https://api.sys.example.com (required unless -cf-config is set)")
+	auth := addAuthFlags(fs)
+	useCFConfig := fs.Bool("cf-config", false, cfConfigUsage+"; provides the defaults of -api, -org, -space and the credentials")
+	orgs := stringsFlag{}
+	fs.Var(&orgs, "org", "organization to discover; can be repeated. All the organizations are discovered when not set")
+	space := fs.String("space", "", "space whose applications are discovered; requires a single -org. All the spaces are discovered when not set")
+	workers := fs.Int("workers", 8, "number of concurrent requests when discovering multiple spaces")
+	summaryPath := fs.String("summary", "", "file where to write the per-space summary of the discovery of multiple spaces")
+	includeCredentials := fs.Bool("include-credentials", false, "include the credentials of the service bindings in the output")
+	apiVersion := fs.String("api-version", "", "version of the Cloud Controller API to use, v2 or v3; detected from the API when not set")
+	recordPath := fs.String("record", "", "file where to record the API responses, to replay the discovery later with -replay")
+	redact := fs.Bool("redact", false, "strip the env values and the credentials from the responses recorded with -record")
+	replayPath := fs.String("replay", "", "file with the API responses recorded with -record; the discovery is run offline against them")
+	skipSSLValidation := fs.Bool("skip-ssl-validation", false, "skip the verification of the API TLS certificate")
+	outputFlags := addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *recordPath != "" && *replayPath != "" {
+		fmt.Fprintln(fs.Output(), "flags -record and -replay are mutually exclusive")
+		fs.Usage()
+		return errUsage
+	}
+	var snapshot *discover.Snapshot
+	if *replayPath != "" {
+		s, err := discover.ReadSnapshot(*replayPath)
+		if err != nil {
+			return err
+		}
+		snapshot = s
+		if *endpoint == "" {
+			*endpoint = snapshot.Endpoint
+		}
+	}
+	cfConfig, err := readCFConfig(*useCFConfig)
+	if err != nil {
+		return err
+	}
+	if cfConfig != nil {
+		if *endpoint == "" {
+			*endpoint = cfConfig.Target
+		}
+		if len(orgs) == 0 && cfConfig.Organization != "" {
+			// Without any flag the targeted space is discovered, like the CF CLI commands do.
+			if *space == "" {
+				*space = cfConfig.Space
+			}
+			if *space != "" {
+				orgs = stringsFlag{cfConfig.Organization}
+			}
+		}
+		*skipSSLValidation = *skipSSLValidation || cfConfig.SkipSSLValidation
+	}
+	if err := requireFlag(fs, "api", *endpoint); err != nil {
+		return err
+	}
+	if *space != "" && len(orgs) != 1 {
+		fmt.Fprintln(stderr, "flag -space requires exactly one -org")
+		fs.Usage()
+		return errUsage
+	}
+	if err := outputFlags.check(fs); err != nil {
+		return err
+	}
+	if v := discover.APIVersion(*apiVersion); v != "" && v != discover.APIVersion2 && v != discover.APIVersion3 {
+		fmt.Fprintf(fs.Output(), "invalid value %q for flag -api-version: must be v2 or v3\n", *apiVersion)
+		fs.Usage()
+		return errUsage
+	}
+
+	ctx := context.Background()
+	client := discover.NewAPIClient(*endpoint, "")
+	client.HTTPClient = newHTTPClient(*skipSSLValidation)
+	var recorder *discover.Recorder
+	switch {
+	case snapshot != nil:
+		// The responses are replayed as they were recorded, so no credentials are needed.
+		client.HTTPClient = &http.Client{Transport: discover.NewReplayer(snapshot)}
+		client.RetryBackoff = 0
+	default:
+		tokens, err := auth.tokenSource(ctx, *endpoint, client.HTTPClient, cfConfig)
+		if err != nil {
+			return err
+		}
+		client.Tokens = tokens
+		if *recordPath != "" {
+			recorder = discover.NewRecorder(*endpoint, client.HTTPClient.Transport, *redact)
+			client.HTTPClient = &http.Client{Transport: recorder}
+		}
+	}
+	d := discover.NewAPIDiscoverer(client)
+	d.IncludeCredentials = *includeCredentials
+	d.Version = discover.APIVersion(*apiVersion)
+	if *space != "" {
+		apps, err := d.DiscoverSpace(ctx, orgs[0], *space)
+		if err := writeSnapshot(recorder, *recordPath, err); err != nil {
+			return err
+		}
+		if len(apps) == 0 {
+			fmt.Fprintf(stderr, "no applications found in space %s of organization %s\n", *space, orgs[0])
+		}
+		return outputFlags.write(stdout, apiRecords(client, apps))
+	}
+
+	// The invalid applications are reported in the summary with the other failures, so that the valid ones are
+	// still written.
+	result, err := d.DiscoverFoundation(ctx, discover.BulkOptions{Organizations: orgs, Workers: *workers, Validate: outputFlags.validate})
+	if err := writeSnapshot(recorder, *recordPath, err); err != nil {
+		return err
+	}
+	printSummary(stderr, result)
+	if *summaryPath != "" {
+		if err := writeSummary(*summaryPath, outputFlags.format, result); err != nil {
+			return err
+		}
+	}
+	if err := outputFlags.writeRecords(stdout, apiRecords(client, result.Applications)); err != nil {
+		return err
+	}
+	if failed := result.Failed(); failed > 0 {
+		return fmt.Errorf("%d applications could not be discovered or are invalid", failed)
+	}
+	return nil
+}
+
+// writeSnapshot writes the responses recorded by recorder, if any, to path, even when the discovery failed with
+// discoveryErr so that the failure can be analyzed offline. It returns discoveryErr, or the error writing the snapshot.
+func writeSnapshot(recorder *discover.Recorder, path string, discoveryErr error) error {
+	if recorder == nil {
+		return discoveryErr
+	}
+	if err := recorder.Snapshot().WriteFile(path); err != nil {
+		return errors.Join(discoveryErr, fmt.Errorf("error writing the snapshot: %w", err))
+	}
+	return discoveryErr
+}
+
+func apiRecords(client *discover.APIClient, apps []discover.Application) []output.Record {
+	records := make([]output.Record, 0, len(apps))
+	for _, app := range apps {
+		records = append(records, output.Record{Application: app, Source: client.Endpoint})
+	}
+	return records
+}
+
+// printSummary reports the number of applications discovered per space and the reason of each failure.
+func printSummary(w io.Writer, result *discover.BulkResult) {
+	for _, s := range result.Spaces {
+		if s.Error != "" {
+			fmt.Fprintf(w, "%s/%s: error listing the applications: %s\n", s.Organization, s.Space, s.Error)
+			continue
+		}
+		fmt.Fprintf(w, "%s/%s: %d discovered, %d failed\n", s.Organization, s.Space, s.Discovered, len(s.Failures))
+		for _, f := range s.Failures {
+			fmt.Fprintf(w, "  %s (%s): %s\n", f.Name, f.GUID, f.Error)
+		}
+	}
+}
+
+func writeSummary(path, format string, result *discover.BulkResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := output.EncodeDocument(f, output.Format(format), result); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func newHTTPClient(skipSSLValidation bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if skipSSLValidation {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{Transport: transport}
+}
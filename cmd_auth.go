@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+)
+
+// Environment variables that provide the secrets, so that they do not show up in the process list.
+const (
+	tokenEnvVar        = "CF_ACCESS_TOKEN"
+	usernameEnvVar     = "CF_USERNAME"
+	passwordEnvVar     = "CF_PASSWORD"
+	clientSecretEnvVar = "CF_CLIENT_SECRET"
+)
+
+// authFlags holds the flags that select how the requests to the Cloud Foundry API are authenticated.
+type authFlags struct {
+	token    string
+	uaa      string
+	username string
+	passcode string
+	clientID string
+}
+
+func addAuthFlags(fs *flag.FlagSet) *authFlags {
+	a := &authFlags{}
+	fs.StringVar(&a.token, "token", "", "OAuth access token; defaults to the value of the "+tokenEnvVar+" environment variable")
+	fs.StringVar(&a.uaa, "uaa", "", "URL of the UAA server; found through the API when not set")
+	fs.StringVar(&a.username, "username", "", "user to log in with; the password is read from the "+passwordEnvVar+" environment variable. Defaults to the value of the "+usernameEnvVar+" environment variable")
+	fs.StringVar(&a.passcode, "passcode", "", "one-time passcode to log in with single sign-on")
+	fs.StringVar(&a.clientID, "client-id", "", "OAuth client to log in with client credentials; the secret is read from the "+clientSecretEnvVar+" environment variable")
+	return a
+}
+
+// tokenSource returns the source of the access tokens for the API at endpoint. Client credentials take precedence
+// over a user login, which takes precedence over a static access token and then over the session of the CF CLI in
+// cfConfig, when not nil.
+func (a *authFlags) tokenSource(ctx context.Context, endpoint string, httpClient *http.Client, cfConfig *discover.CFConfig) (discover.TokenSource, error) {
+	if a.username == "" {
+		a.username = os.Getenv(usernameEnvVar)
+	}
+	var grant discover.Grant
+	uaa := &discover.UAAClient{Endpoint: a.uaa, HTTPClient: httpClient}
+	switch {
+	case a.clientID != "":
+		uaa.ClientID, uaa.ClientSecret = a.clientID, os.Getenv(clientSecretEnvVar)
+		if uaa.ClientSecret == "" {
+			return nil, fmt.Errorf("flag -client-id requires the %s environment variable", clientSecretEnvVar)
+		}
+		grant = discover.ClientCredentialsGrant()
+	case a.passcode != "":
+		grant = discover.PasscodeGrant(a.passcode)
+	case a.username != "":
+		password := os.Getenv(passwordEnvVar)
+		if password == "" {
+			return nil, fmt.Errorf("logging in as %s requires the %s environment variable", a.username, passwordEnvVar)
+		}
+		grant = discover.PasswordGrant(a.username, password)
+	default:
+		if a.token == "" {
+			a.token = os.Getenv(tokenEnvVar)
+		}
+		if a.token != "" {
+			return discover.StaticToken(a.token), nil
+		}
+		if cfConfig != nil {
+			return cfConfig.TokenSource(httpClient)
+		}
+		return nil, errors.New("credentials are required: use the -token, -username, -passcode, -client-id or -cf-config flags")
+	}
+	if uaa.Endpoint == "" && cfConfig != nil && cfConfig.Target == endpoint {
+		uaa.Endpoint = cfConfig.UAAEndpoint
+	}
+	if uaa.Endpoint == "" {
+		var err error
+		if uaa.Endpoint, err = discover.FindUAAEndpoint(ctx, endpoint, httpClient); err != nil {
+			return nil, err
+		}
+	}
+	return discover.NewUAATokenSource(uaa, grant), nil
+}
+
+// cfConfigUsage is the usage of the -cf-config flag shared by the commands.
+const cfConfigUsage = "reuse the session of the CF CLI stored in $CF_HOME/.cf/config.json or ~/.cf/config.json"
+
+// readCFConfig reads the session of the CF CLI when enabled is true, and returns nil otherwise.
+func readCFConfig(enabled bool) (*discover.CFConfig, error) {
+	if !enabled {
+		return nil, nil
+	}
+	path, err := discover.CFConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return discover.ReadCFConfig(path)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	"github.com/gciavarrini/cf-application-discovery/pkg/generate/helm"
+	"github.com/gciavarrini/cf-application-discovery/pkg/generate/kubernetes"
+	"github.com/gciavarrini/cf-application-discovery/pkg/generate/kustomize"
+	"github.com/gciavarrini/cf-application-discovery/pkg/output"
+	"gopkg.in/yaml.v3"
+)
+
+// Targets supported by the generate command.
+const (
+	kubernetesTarget = "kubernetes"
+	helmTarget       = "helm"
+	kustomizeTarget  = "kustomize"
+	envTarget        = "env"
+)
+
+var generateTargets = []string{kubernetesTarget, helmTarget, kustomizeTarget, envTarget}
+
+func runGenerate(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("generate", stderr)
+	inputs := stringsFlag{}
+	fs.Var(&inputs, "input", "file or directory with the applications discovered by the manifest or api commands, or - for stdin (required); can be repeated")
+	target := fs.String("target", kubernetesTarget, "artifacts to generate: "+strings.Join(generateTargets, ", "))
+	namespace := fs.String("namespace", "", "namespace of the generated resources; defaults to the space of each application")
+	imageRegistry := fs.String("image-registry", "", "registry of the images built for the applications that are deployed with buildpacks")
+	openShiftRoutes := fs.Bool("openshift-routes", false, "kubernetes and kustomize targets only: expose the routes with OpenShift Routes instead of Ingresses")
+	ingressClass := fs.String("ingress-class", "", "class of the Ingresses generated for the HTTP routes; the default class of the cluster is used when not set")
+	cfEnv := fs.Bool("cf-env", false, "kubernetes and kustomize targets only: set VCAP_SERVICES, VCAP_APPLICATION and the other variables that Cloud Foundry sets in the containers")
+	resourcePolicy := fs.String("resource-policy", "", "YAML file with the policy that derives the requests and limits of the containers from the memory and disk quotas")
+	path := fs.String("output", "", "file where to write the generated artifacts; defaults to stdout")
+	dir := fs.String("output-dir", "", "directory where to write the artifacts of each application in a separate file; required by the helm, kustomize and env targets")
+	chartPer := fs.String("chart-per", string(helm.SpaceLayout), "helm target only: generate a chart per "+string(helm.SpaceLayout)+" or per "+string(helm.ApplicationLayout))
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlag(fs, "input", inputs.String()); err != nil {
+		return err
+	}
+	if *path != "" && *dir != "" {
+		fmt.Fprintln(fs.Output(), "flags -output and -output-dir are mutually exclusive")
+		fs.Usage()
+		return errUsage
+	}
+	if !slices.Contains(generateTargets, *target) {
+		fmt.Fprintf(fs.Output(), "invalid value %q for flag -target: must be one of %s\n", *target, strings.Join(generateTargets, ", "))
+		fs.Usage()
+		return errUsage
+	}
+	if layout := helm.Layout(*chartPer); layout != helm.SpaceLayout && layout != helm.ApplicationLayout {
+		fmt.Fprintf(fs.Output(), "invalid value %q for flag -chart-per: must be %s or %s\n", *chartPer, helm.SpaceLayout, helm.ApplicationLayout)
+		fs.Usage()
+		return errUsage
+	}
+	// The charts only render Ingresses for the routes.
+	if *openShiftRoutes && *target == helmTarget {
+		fmt.Fprintf(fs.Output(), "flag -openshift-routes is not supported by the %s target\n", helmTarget)
+		fs.Usage()
+		return errUsage
+	}
+	// The charts only render the resources exposed in their values, which have no VCAP variables.
+	if *cfEnv && *target == helmTarget {
+		fmt.Fprintf(fs.Output(), "flag -cf-env is not supported by the %s target\n", helmTarget)
+		fs.Usage()
+		return errUsage
+	}
+	if *target == helmTarget || *target == kustomizeTarget || *target == envTarget {
+		if err := requireFlag(fs, "output-dir", *dir); err != nil {
+			return err
+		}
+	}
+
+	apps, err := readApplications(inputs)
+	if err != nil {
+		return err
+	}
+	opts := kubernetes.Options{Namespace: *namespace, ImageRegistry: *imageRegistry, IngressClassName: *ingressClass, OpenShiftRoutes: *openShiftRoutes, CFEnv: *cfEnv}
+	if *resourcePolicy != "" {
+		if opts.Resources, err = readResourcePolicy(*resourcePolicy); err != nil {
+			return err
+		}
+	}
+	switch *target {
+	case helmTarget:
+		return generateCharts(stderr, *dir, apps, helm.Options{Layout: helm.Layout(*chartPer), Kubernetes: opts})
+	case kustomizeTarget:
+		return generateKustomizeLayouts(stderr, *dir, apps, kustomize.Options{Kubernetes: opts})
+	case envTarget:
+		return writeEnvFiles(*dir, *namespace, apps)
+	}
+	results := make([]*kubernetes.Result, 0, len(apps))
+	for _, app := range apps {
+		r, err := kubernetes.Generate(app, opts)
+		if err != nil {
+			return err
+		}
+		printWarnings(stderr, r.Warnings)
+		results = append(results, r)
+	}
+	if *dir != "" {
+		return writeObjectsDirectory(*dir, apps, results)
+	}
+	objects := []kubernetes.Object{}
+	for _, r := range results {
+		objects = append(objects, r.Objects...)
+	}
+	return writeObjects(stdout, *path, objects)
+}
+
+// generateCharts writes a Helm chart per space or per application into dir.
+func generateCharts(stderr io.Writer, dir string, apps []discover.Application, opts helm.Options) error {
+	r, err := helm.Generate(apps, opts)
+	if err != nil {
+		return err
+	}
+	printWarnings(stderr, r.Warnings)
+	for _, c := range r.Charts {
+		if err := c.Write(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateKustomizeLayouts writes a Kustomize base and overlays per application into dir.
+func generateKustomizeLayouts(stderr io.Writer, dir string, apps []discover.Application, opts kustomize.Options) error {
+	r, err := kustomize.Generate(apps, opts)
+	if err != nil {
+		return err
+	}
+	printWarnings(stderr, r.Warnings)
+	for _, l := range r.Layouts {
+		if err := l.Write(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeEnvFiles writes the variables of each process to `<dir>/<namespace>/<workload>.env`, in the format of the
+// `--env-file` flag of `docker run`.
+func writeEnvFiles(dir, namespace string, apps []discover.Application) error {
+	used := map[string]bool{}
+	for _, app := range apps {
+		ns := namespace
+		if ns == "" {
+			ns = "default"
+			if app.Metadata.Space != "" {
+				ns = kubernetes.ResourceName(app.Metadata.Space)
+			}
+		}
+		for _, p := range kubernetes.Processes(app) {
+			content, err := kubernetes.EnvFile(app, p)
+			if err != nil {
+				return err
+			}
+			file := filepath.Join(dir, ns, kubernetes.WorkloadName(app, p.Type))
+			for n := 2; used[file]; n++ {
+				file = filepath.Join(dir, ns, fmt.Sprintf("%s-%d", kubernetes.WorkloadName(app, p.Type), n))
+			}
+			used[file] = true
+			if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(file+".env", content, 0o600); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func printWarnings(w io.Writer, warnings []string) {
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "warning: %s\n", warning)
+	}
+}
+
+// readApplications reads the discovered applications in the inputs, in order.
+func readApplications(inputs []string) ([]discover.Application, error) {
+	apps := []discover.Application{}
+	for _, in := range inputs {
+		found, err := output.ReadApplications(in)
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, found...)
+	}
+	return apps, nil
+}
+
+// readResourcePolicy reads the resource policy from the YAML file at path, rejecting the unknown fields.
+func readResourcePolicy(path string) (kubernetes.ResourcePolicy, error) {
+	policy := kubernetes.ResourcePolicy{}
+	f, err := os.Open(path)
+	if err != nil {
+		return policy, err
+	}
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return policy, fmt.Errorf("error reading the resource policy %s: %w", path, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return policy, fmt.Errorf("invalid resource policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// writeObjects writes the objects as YAML documents to the file at path, or to stdout when path is empty.
+func writeObjects(stdout io.Writer, path string, objects []kubernetes.Object) error {
+	if path == "" {
+		return output.Encode(stdout, output.YAMLFormat, objects)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := output.Encode(f, output.YAMLFormat, objects); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeObjectsDirectory writes the objects generated for each application to `<dir>/<namespace>/<app>.yaml`.
+func writeObjectsDirectory(dir string, apps []discover.Application, results []*kubernetes.Result) error {
+	used := map[string]bool{}
+	for i, r := range results {
+		if len(r.Objects) == 0 {
+			continue
+		}
+		namespace := r.Objects[0].GetObjectMeta().Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		file := filepath.Join(dir, namespace, kubernetes.ResourceName(apps[i].Metadata.Name))
+		for n := 2; used[file]; n++ {
+			file = filepath.Join(dir, namespace, fmt.Sprintf("%s-%d", kubernetes.ResourceName(apps[i].Metadata.Name), n))
+		}
+		used[file] = true
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		if err := writeObjects(nil, file+".yaml", r.Objects); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+)
+
+func runLint(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("lint", stderr)
+	manifestPaths := stringsFlag{}
+	fs.Var(&manifestPaths, "manifest", "path to the CF application manifest (required); can be repeated to merge overlay manifests into the first one")
+	varsFlags := addVarsFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlag(fs, "manifest", manifestPaths.String()); err != nil {
+		return err
+	}
+
+	vars, err := varsFlags.load()
+	if err != nil {
+		return err
+	}
+	manifestPath := manifestPaths.String()
+	m, err := discover.ReadLayeredManifest(manifestPaths, vars)
+	if err != nil {
+		return err
+	}
+	if len(m.Applications) == 0 {
+		return fmt.Errorf("%s: no applications found", manifestPath)
+	}
+	failed := 0
+	for _, cfApp := range m.Applications {
+		app, err := discover.Discover(*cfApp, m.Version, m.Space)
+		if err == nil {
+			err = discover.Validate(app)
+		}
+		var violations discover.ValidationErrors
+		switch {
+		case errors.As(err, &violations):
+			for _, v := range violations {
+				fmt.Fprintf(stdout, "%s: application %q: %v\n", manifestPath, cfApp.Name, v)
+			}
+			failed++
+		case err != nil:
+			fmt.Fprintf(stdout, "%s: application %q: %v\n", manifestPath, cfApp.Name, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.New("manifest contains errors")
+	}
+	fmt.Fprintf(stdout, "%s: %d applications OK\n", manifestPath, len(m.Applications))
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	"github.com/gciavarrini/cf-application-discovery/pkg/output"
+)
+
+func runManifest(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("manifest", stderr)
+	manifestPaths := stringsFlag{}
+	fs.Var(&manifestPaths, "manifest", "path to the CF application manifest (required); can be repeated to merge overlay manifests into the first one")
+	space := fs.String("space", "", "space assigned to the discovered applications; overrides the space field in the manifest")
+	useCFConfig := fs.Bool("cf-config", false, cfConfigUsage+"; the targeted space is the default of -space")
+	outputFlags := addOutputFlags(fs)
+	inspectSource := fs.Bool("inspect-source", false, "inspect the source code referenced by the path of each application")
+	varsFlags := addVarsFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlag(fs, "manifest", manifestPaths.String()); err != nil {
+		return err
+	}
+	if err := outputFlags.check(fs); err != nil {
+		return err
+	}
+
+	cfConfig, err := readCFConfig(*useCFConfig)
+	if err != nil {
+		return err
+	}
+	if *space == "" && cfConfig != nil {
+		*space = cfConfig.Space
+	}
+	vars, err := varsFlags.load()
+	if err != nil {
+		return err
+	}
+	apps, err := discoverManifest(manifestPaths, *space, vars)
+	if err != nil {
+		return err
+	}
+	if len(apps) == 0 {
+		fmt.Fprintf(stderr, "no applications found in %s\n", manifestPaths.String())
+	}
+	if *inspectSource {
+		inspectSources(apps, stderr)
+	}
+	records := make([]output.Record, 0, len(apps))
+	for _, app := range apps {
+		records = append(records, output.Record{Application: app, Source: manifestPaths.String()})
+	}
+	return outputFlags.write(stdout, records)
+}
+
+// varsFlags holds the flags used to resolve the variables in the manifest.
+type varsFlags struct {
+	files stringsFlag
+	vars  stringsFlag
+}
+
+func addVarsFlags(fs *flag.FlagSet) *varsFlags {
+	v := &varsFlags{}
+	fs.Var(&v.files, "vars-file", "path to a YAML file with the values of the manifest variables; can be repeated")
+	fs.Var(&v.vars, "var", "value of a manifest variable in key=value format; can be repeated and overrides the vars files")
+	return v
+}
+
+func (v *varsFlags) load() (discover.Variables, error) {
+	return discover.LoadVariables(v.files, v.vars)
+}
+
+// discoverManifest reads the manifests at paths, merging them in order, and discovers each of the resulting applications.
+func discoverManifest(paths []string, space string, vars discover.Variables) ([]discover.Application, error) {
+	m, err := discover.ReadLayeredManifest(paths, vars)
+	if err != nil {
+		return nil, err
+	}
+	if space == "" {
+		space = m.Space
+	}
+	apps := make([]discover.Application, 0, len(m.Applications))
+	for _, cfApp := range m.Applications {
+		app, err := discover.Discover(*cfApp, m.Version, space)
+		if err != nil {
+			return nil, fmt.Errorf("application %q: %w", cfApp.Name, err)
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+// inspectSources records the source code information of the applications that define a path. Applications
+// whose source can't be inspected are reported in stderr without failing the discovery.
+func inspectSources(apps []discover.Application, stderr io.Writer) {
+	for i := range apps {
+		if apps[i].Path == "" {
+			continue
+		}
+		src, err := discover.InspectSource(apps[i].Path)
+		if err != nil {
+			fmt.Fprintf(stderr, "warning: application %q: %v\n", apps[i].Metadata.Name, err)
+			continue
+		}
+		apps[i].Source = src
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	"github.com/gciavarrini/cf-application-discovery/pkg/output"
+)
+
+// outputFlags holds the flags that control how the discovered applications are validated and written.
+type outputFlags struct {
+	format   string
+	path     string
+	dir      string
+	validate bool
+}
+
+func addOutputFlags(fs *flag.FlagSet) *outputFlags {
+	o := &outputFlags{}
+	fs.StringVar(&o.format, "format", string(output.YAMLFormat), "output format: yaml or json")
+	fs.StringVar(&o.path, "output", "", "file where to write the discovered applications; defaults to stdout")
+	fs.StringVar(&o.dir, "output-dir", "", "directory where to write one file per discovered application and an index file")
+	fs.BoolVar(&o.validate, "validate", true, "validate the discovered applications and fail when they violate any constraint")
+	return o
+}
+
+// check verifies that the output flags are consistent.
+func (o *outputFlags) check(fs *flag.FlagSet) error {
+	if o.path != "" && o.dir != "" {
+		fmt.Fprintln(fs.Output(), "flags -output and -output-dir are mutually exclusive")
+		fs.Usage()
+		return errUsage
+	}
+	_, err := output.ParseFormat(o.format)
+	return err
+}
+
+// write validates the applications, when requested, and writes them to the configured destination.
+func (o *outputFlags) write(stdout io.Writer, records []output.Record) error {
+	if o.validate {
+		apps := make([]discover.Application, 0, len(records))
+		for _, r := range records {
+			apps = append(apps, r.Application)
+		}
+		if err := validateApplications(apps); err != nil {
+			return err
+		}
+	}
+	return o.writeRecords(stdout, records)
+}
+
+// writeRecords writes the applications to the configured destination without validating them.
+func (o *outputFlags) writeRecords(stdout io.Writer, records []output.Record) error {
+	format, err := output.ParseFormat(o.format)
+	if err != nil {
+		return err
+	}
+	if o.dir != "" {
+		_, err := output.WriteDirectory(o.dir, format, records)
+		return err
+	}
+	apps := make([]discover.Application, 0, len(records))
+	for _, r := range records {
+		apps = append(apps, r.Application)
+	}
+	return writeApplications(stdout, o.path, format, apps)
+}
+
+// validateApplications validates each application and reports the violations of all of them.
+func validateApplications(apps []discover.Application) error {
+	errs := []error{}
+	for _, app := range apps {
+		if err := discover.Validate(app); err != nil {
+			errs = append(errs, fmt.Errorf("application %q is invalid: %w", app.Metadata.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeApplications encodes apps into the file at path, or into stdout when path is empty.
+func writeApplications(stdout io.Writer, path string, format output.Format, apps []discover.Application) error {
+	if path == "" {
+		return output.Encode(stdout, format, apps)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := output.Encode(f, format, apps); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
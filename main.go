@@ -1,55 +1,125 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"strings"
+)
 
-	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+const programName = "discover"
 
-	"gopkg.in/yaml.v3"
+// Exit codes returned by the CLI.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
 )
 
-func main() {
+// errUsage signals that the command failed because of invalid arguments. The
+// details have already been reported by the flag set.
+var errUsage = errors.New("invalid usage")
 
-	// Check if a file path is provided as an argument
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run main.go <path_to_manifest.yml>")
-		return
-	}
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{name: "manifest", summary: "Discover applications from CF application manifest files", run: runManifest},
+	{name: "api", summary: "Discover applications from a live Cloud Foundry API", run: runAPI},
+	{name: "lint", summary: "Check CF application manifests for errors", run: runLint},
+	{name: "generate", summary: "Generate deployment artifacts from discovered applications", run: runGenerate},
+}
 
-	var manifestFilePath = os.Args[1]
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
 
-	// Read the YAML file
-	data, err := os.ReadFile(manifestFilePath)
-	if err != nil {
-		fmt.Printf("Error reading YAML file: %v\n", err)
-		return
+// run executes the subcommand in args and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+	name := args[0]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		usage(stdout)
+		return exitOK
+	}
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		err := c.run(args[1:], stdout, stderr)
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+			return exitOK
+		case errors.Is(err, errUsage):
+			return exitUsage
+		default:
+			fmt.Fprintf(stderr, "%s %s: %v\n", programName, name, err)
+			return exitFailure
+		}
 	}
+	fmt.Fprintf(stderr, "%s: unknown command %q\n\n", programName, name)
+	usage(stderr)
+	return exitUsage
+}
 
-	// Unmarshal the YAML data into the Manifest struct
-	var cfApplications discover.Manifest
-	err = yaml.Unmarshal(data, &cfApplications)
-	if err != nil {
-		fmt.Printf("Error unmarshalling YAML: %v\n", err)
-		return
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\nCommands:\n", programName)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
 	}
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for the flags accepted by each command.\n", programName)
+}
+
+// newFlagSet creates a flag set for the named subcommand that reports errors
+// instead of exiting the process.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(programName+" "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
 
-	if len(cfApplications.Applications) > 0 {
-		for i, v := range cfApplications.Applications {
-			d, err := discover.Discover(*v, cfApplications.Version, cfApplications.Space)
-			if err != nil {
-				log.Fatal(err)
-			}
-			m, err := yaml.Marshal(d)
-			if err != nil {
-				fmt.Printf("Error marshalling YAML file: %v\n", err)
-				return
-			}
-			fmt.Printf("#%d\n%s\n", i, m)
+// parseFlags parses args into fs and rejects unexpected positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
 		}
-	} else {
-		fmt.Println("No applications found.")
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %v\n", fs.Args())
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// requireFlag reports a usage error when the named flag has not been set.
+func requireFlag(fs *flag.FlagSet, name, value string) error {
+	if value != "" {
+		return nil
 	}
+	fmt.Fprintf(fs.Output(), "flag -%s is required\n", name)
+	fs.Usage()
+	return errUsage
+}
+
+// stringsFlag is a flag that can be repeated to accumulate values.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
 
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const manifestPath = "resources/cloud_foundry/testdata/cf_complex_example.yaml"
+
+var _ = Describe("CLI", func() {
+	DescribeTable("reports the usage errors", func(args []string, expectedCode int, expectedStderr string) {
+		stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+		Expect(run(args, &stdout, &stderr)).To(Equal(expectedCode))
+		Expect(stdout.String()).To(BeEmpty())
+		Expect(stderr.String()).To(ContainSubstring(expectedStderr))
+	},
+		Entry("without command", []string{}, exitUsage, "Usage: discover <command> [flags]"),
+		Entry("with an unknown command", []string{"deploy"}, exitUsage, `discover: unknown command "deploy"`),
+		Entry("with an unknown flag", []string{"manifest", "-foo"}, exitUsage, "flag provided but not defined: -foo"),
+		Entry("with unexpected arguments", []string{"manifest", "-manifest", manifestPath, "extra"}, exitUsage, "unexpected arguments: [extra]"),
+		Entry("without a required flag", []string{"manifest"}, exitUsage, "flag -manifest is required"),
+		Entry("with -output and -output-dir", []string{"manifest", "-manifest", manifestPath, "-output", "apps.yaml", "-output-dir", "out"}, exitUsage,
+			"flags -output and -output-dir are mutually exclusive"),
+		Entry("with an invalid format", []string{"manifest", "-manifest", manifestPath, "-format", "xml"}, exitFailure, `discover manifest: unsupported output format "xml"`),
+		Entry("without the input of generate", []string{"generate"}, exitUsage, "flag -input is required"),
+		Entry("with an invalid target", []string{"generate", "-input", "-", "-target", "terraform"}, exitUsage, `invalid value "terraform" for flag -target`),
+		Entry("with a target that needs an output directory", []string{"generate", "-input", "-", "-target", "helm"}, exitUsage, "flag -output-dir is required"),
+		Entry("with -openshift-routes and the helm target", []string{"generate", "-input", "-", "-target", "helm", "-output-dir", "charts", "-openshift-routes"}, exitUsage,
+			"flag -openshift-routes is not supported by the helm target"),
+		Entry("with -cf-env and the helm target", []string{"generate", "-input", "-", "-target", "helm", "-output-dir", "charts", "-cf-env"}, exitUsage,
+			"flag -cf-env is not supported by the helm target"),
+		Entry("with a missing manifest", []string{"manifest", "-manifest", "missing.yaml"}, exitFailure, "discover manifest: error reading manifest missing.yaml"),
+	)
+
+	It("prints the usage with help", func() {
+		stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+		Expect(run([]string{"help"}, &stdout, &stderr)).To(Equal(exitOK))
+		Expect(stdout.String()).To(ContainSubstring("manifest   Discover applications from CF application manifest files"))
+		Expect(stderr.String()).To(BeEmpty())
+	})
+
+	It("prints the flags of a command with -h", func() {
+		stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+		Expect(run([]string{"manifest", "-h"}, &stdout, &stderr)).To(Equal(exitOK))
+		Expect(stderr.String()).To(ContainSubstring("Usage of discover manifest:"))
+	})
+
+	It("discovers the applications of a manifest", func() {
+		stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+		Expect(run([]string{"manifest", "-manifest", manifestPath}, &stdout, &stderr)).To(Equal(exitOK))
+		Expect(stderr.String()).To(BeEmpty())
+		Expect(stdout.String()).To(ContainSubstring("name: my-web-app"))
+	})
+
+	It("writes the applications of a manifest to a file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "apps.json")
+		stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+		Expect(run([]string{"manifest", "-manifest", manifestPath, "-format", "json", "-output", path}, &stdout, &stderr)).To(Equal(exitOK))
+		Expect(stdout.String()).To(BeEmpty())
+		content, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(ContainSubstring(`"name": "my-web-app"`))
+	})
+})
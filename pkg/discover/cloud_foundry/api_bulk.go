@@ -0,0 +1,197 @@
+package cloud_foundry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BulkOptions configures the discovery of the applications in multiple organizations.
+type BulkOptions struct {
+	// Organizations restricts the discovery to the organizations with these names. All the organizations visible
+	// to the user are discovered when empty.
+	Organizations []string
+	// Workers is the number of applications that are discovered concurrently. Defaults to 1.
+	Workers int
+	// Validate records the applications that violate the constraints checked by Validate as failures, so that
+	// they are left out of the applications of the result.
+	Validate bool
+}
+
+// BulkResult contains the outcome of the discovery of multiple organizations.
+type BulkResult struct {
+	// Applications contains the applications that were discovered successfully, grouped by organization and space.
+	Applications []Application `yaml:"-"`
+	// Spaces summarizes the outcome of the discovery for each space.
+	Spaces []SpaceSummary `yaml:"spaces"`
+}
+
+// Failed returns the number of applications that could not be discovered, including the ones in spaces that could not
+// be listed.
+func (r *BulkResult) Failed() int {
+	failed := 0
+	for _, s := range r.Spaces {
+		failed += len(s.Failures)
+		if s.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// SpaceSummary summarizes the outcome of the discovery of the applications in a space.
+type SpaceSummary struct {
+	Organization string `yaml:"organization"`
+	Space        string `yaml:"space"`
+	// Discovered is the number of applications discovered successfully.
+	Discovered int `yaml:"discovered"`
+	// Failures lists the applications that could not be discovered, or that are invalid.
+	Failures []AppFailure `yaml:"failures,omitempty"`
+	// Error captures the reason why the applications in the space could not be listed.
+	Error string `yaml:"error,omitempty"`
+}
+
+// AppFailure describes an application that could not be discovered, or that is invalid.
+type AppFailure struct {
+	Name  string `yaml:"name"`
+	GUID  string `yaml:"guid"`
+	Error string `yaml:"error"`
+}
+
+type spaceRef struct {
+	guid  string
+	name  string
+	org   string
+	index int
+}
+
+type appRef struct {
+	guid  string
+	name  string
+	space spaceRef
+}
+
+type appOutcome struct {
+	ref appRef
+	app Application
+	err error
+}
+
+// DiscoverFoundation discovers the applications in every space of the selected organizations, using a pool of workers
+// to send the requests concurrently. Failures to discover an application, or to list the applications in a space, are
+// recorded in the summary of the space instead of stopping the discovery. An error is only returned when the
+// organizations or their spaces can't be listed. When opts.Validate is set, the invalid applications are recorded as
+// failures too.
+func (d *APIDiscoverer) DiscoverFoundation(ctx context.Context, opts BulkOptions) (*BulkResult, error) {
+	if err := d.detectVersion(ctx); err != nil {
+		return nil, err
+	}
+	spaces, err := d.listSpaces(ctx, opts.Organizations, opts.Workers)
+	if err != nil {
+		return nil, err
+	}
+	result := &BulkResult{Applications: []Application{}, Spaces: make([]SpaceSummary, len(spaces))}
+	spaceApps := make([][]resource, len(spaces))
+	forEach(ctx, len(spaces), opts.Workers, func(i int) {
+		s := spaces[i]
+		result.Spaces[i] = SpaceSummary{Organization: s.org, Space: s.name}
+		resources, err := d.listApps(ctx, s.guid)
+		if err != nil {
+			result.Spaces[i].Error = err.Error()
+			return
+		}
+		spaceApps[i] = resources
+	})
+	apps := []appRef{}
+	for i, resources := range spaceApps {
+		for _, r := range resources {
+			apps = append(apps, appRef{guid: r.GUID, name: r.Name, space: spaces[i]})
+		}
+	}
+
+	outcomes := make([]appOutcome, len(apps))
+	forEach(ctx, len(apps), opts.Workers, func(i int) {
+		ref := apps[i]
+		app, err := d.DiscoverApp(ctx, ref.guid, ref.space.org, ref.space.name)
+		if err == nil && opts.Validate {
+			if verr := Validate(app); verr != nil {
+				err = fmt.Errorf("invalid application: %w", verr)
+			}
+		}
+		outcomes[i] = appOutcome{ref: ref, app: app, err: err}
+	})
+	for _, o := range outcomes {
+		summary := &result.Spaces[o.ref.space.index]
+		if o.err != nil {
+			summary.Failures = append(summary.Failures, AppFailure{Name: o.ref.name, GUID: o.ref.guid, Error: o.err.Error()})
+			continue
+		}
+		summary.Discovered++
+		result.Applications = append(result.Applications, o.app)
+	}
+	return result, ctx.Err()
+}
+
+// forEach calls fn for each index in [0, n) using a pool of workers. Indexes that are pending when the context is
+// cancelled are skipped.
+func forEach(ctx context.Context, n, workers int, fn func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// listSpaces lists the spaces of the organizations with the given names, or of all the organizations when names is empty.
+func (d *APIDiscoverer) listSpaces(ctx context.Context, names []string, workers int) ([]spaceRef, error) {
+	orgs, err := d.listOrganizations(ctx, names)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the organizations: %w", err)
+	}
+	for _, n := range names {
+		if !containsResource(orgs, n) {
+			return nil, fmt.Errorf("organization %s not found", n)
+		}
+	}
+	orgSpaces := make([][]resource, len(orgs))
+	errs := make([]error, len(orgs))
+	forEach(ctx, len(orgs), workers, func(i int) {
+		orgSpaces[i], errs[i] = d.listSpacesOf(ctx, orgs[i].GUID, "")
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	spaces := []spaceRef{}
+	for i, org := range orgs {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("error listing the spaces of organization %s: %w", org.Name, errs[i])
+		}
+		for _, s := range orgSpaces[i] {
+			spaces = append(spaces, spaceRef{guid: s.GUID, name: s.Name, org: org.Name, index: len(spaces)})
+		}
+	}
+	return spaces, nil
+}
+
+func containsResource(resources []resource, name string) bool {
+	for _, r := range resources {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
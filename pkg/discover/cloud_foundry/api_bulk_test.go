@@ -0,0 +1,124 @@
+package cloud_foundry
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bulk API discovery", func() {
+	var (
+		cc         *fakeCloudController
+		discoverer *APIDiscoverer
+	)
+
+	BeforeEach(func() {
+		cc = newFakeCloudController()
+		client := NewAPIClient(cc.URL, "token")
+		client.RetryBackoff = time.Millisecond
+		discoverer = NewAPIDiscoverer(client)
+
+		cc.handle("/v3/organizations", nil, http.StatusOK, list("", named("org-1", "team-a"), named("org-2", "team-b")))
+		cc.handle("/v3/organizations", url.Values{"names": {"team-b"}}, http.StatusOK, list("", named("org-2", "team-b")))
+		cc.handle("/v3/spaces", url.Values{"organization_guids": {"org-1"}}, http.StatusOK, list("", named("space-1", "dev"), named("space-2", "prod")))
+		cc.handle("/v3/spaces", url.Values{"organization_guids": {"org-2"}}, http.StatusOK,
+			list(cc.URL+"/v3/spaces?organization_guids=org-2&page=2", named("space-3", "dev")))
+		cc.handle("/v3/spaces", url.Values{"organization_guids": {"org-2"}, "page": {"2"}}, http.StatusOK, list("", named("space-4", "broken")))
+
+		cc.handle("/v3/apps", url.Values{"space_guids": {"space-1"}}, http.StatusOK, list("", named("app-1", "foo"), named("app-2", "bar")))
+		cc.handle("/v3/apps", url.Values{"space_guids": {"space-2"}}, http.StatusOK, list("", named("app-3", "foo")))
+		cc.handle("/v3/apps", url.Values{"space_guids": {"space-3"}}, http.StatusOK, list("", named("app-4", "baz")))
+		cc.handle("/v3/apps", url.Values{"space_guids": {"space-4"}}, http.StatusForbidden, `{"errors":[{"code":10003,"title":"CF-NotAuthorized","detail":"You are not authorized to perform the requested action"}]}`)
+
+		cc.registerApp("app-1", "applications:\n- name: foo\n")
+		cc.registerApp("app-3", "applications:\n- name: foo\n  instances: 3\n")
+		cc.registerApp("app-4", "applications:\n- name: baz\n")
+		// The first attempt to retrieve the manifest of app-4 is throttled.
+		cc.responses["/v3/apps/app-4/manifest"] = append([]fakeResponse{{status: http.StatusTooManyRequests, body: "{}"}}, cc.responses["/v3/apps/app-4/manifest"]...)
+		// The manifest of app-2 can't be retrieved.
+		cc.handle("/v3/apps/app-2/manifest", nil, http.StatusBadGateway, "{}")
+	})
+
+	When("discovering all the organizations", func() {
+		It("discovers the applications and summarizes the failures per space", func() {
+			result, err := discoverer.DiscoverFoundation(context.Background(), BulkOptions{Workers: 4})
+			Expect(err).NotTo(HaveOccurred())
+
+			names := []string{}
+			for _, app := range result.Applications {
+				names = append(names, app.Metadata.Organization+"/"+app.Metadata.Space+"/"+app.Metadata.Name)
+			}
+			Expect(names).To(Equal([]string{"team-a/dev/foo", "team-a/prod/foo", "team-b/dev/baz"}))
+			Expect(result.Applications[1].Instances).To(Equal(3))
+
+			Expect(result.Spaces).To(HaveLen(4))
+			Expect(result.Spaces[0].Organization).To(Equal("team-a"))
+			Expect(result.Spaces[0].Space).To(Equal("dev"))
+			Expect(result.Spaces[0].Discovered).To(Equal(1))
+			Expect(result.Spaces[0].Failures).To(HaveLen(1))
+			Expect(result.Spaces[0].Failures[0].Name).To(Equal("bar"))
+			Expect(result.Spaces[0].Failures[0].GUID).To(Equal("app-2"))
+			Expect(result.Spaces[0].Failures[0].Error).To(ContainSubstring("unexpected status code 502"))
+			Expect(result.Spaces[1]).To(Equal(SpaceSummary{Organization: "team-a", Space: "prod", Discovered: 1}))
+			Expect(result.Spaces[2]).To(Equal(SpaceSummary{Organization: "team-b", Space: "dev", Discovered: 1}))
+			Expect(result.Spaces[3].Space).To(Equal("broken"))
+			Expect(result.Spaces[3].Error).To(ContainSubstring("CF-NotAuthorized"))
+			Expect(result.Failed()).To(Equal(2))
+		})
+
+		It("retries the requests that fail with a 5xx status code", func() {
+			_, err := discoverer.DiscoverFoundation(context.Background(), BulkOptions{Workers: 2})
+			Expect(err).NotTo(HaveOccurred())
+			attempts := 0
+			for _, r := range cc.requests {
+				if r.URL.Path == "/v3/apps/app-2/manifest" {
+					attempts++
+				}
+			}
+			Expect(attempts).To(Equal(1 + defaultMaxRetries))
+		})
+
+		It("records the invalid applications as failures when validating", func() {
+			delete(cc.responses, "/v3/apps/app-3/manifest")
+			cc.registerApp("app-3", "applications:\n- name: foo\n  processes:\n  - type: web\n    memory: -1\n")
+			result, err := discoverer.DiscoverFoundation(context.Background(), BulkOptions{Workers: 4, Validate: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Applications).To(HaveLen(2))
+			Expect(result.Spaces[1]).To(Equal(SpaceSummary{Organization: "team-a", Space: "prod", Failures: []AppFailure{{
+				Name:  "foo",
+				GUID:  "app-3",
+				Error: `invalid application: processes[0].memory: must be an amount like 512M or 1G, got "-1"`,
+			}}}))
+			Expect(result.Failed()).To(Equal(3))
+		})
+	})
+
+	When("discovering selected organizations", func() {
+		It("only discovers the spaces in those organizations", func() {
+			result, err := discoverer.DiscoverFoundation(context.Background(), BulkOptions{Organizations: []string{"team-b"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Spaces).To(HaveLen(2))
+			Expect(result.Applications).To(HaveLen(1))
+			Expect(result.Applications[0].Metadata.Name).To(Equal("baz"))
+		})
+
+		It("fails when an organization does not exist", func() {
+			cc.handle("/v3/organizations", url.Values{"names": {"team-c"}}, http.StatusOK, list(""))
+			_, err := discoverer.DiscoverFoundation(context.Background(), BulkOptions{Organizations: []string{"team-c"}})
+			Expect(err).To(MatchError("organization team-c not found"))
+		})
+	})
+
+	When("the context is cancelled", func() {
+		It("stops the discovery", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := discoverer.DiscoverFoundation(ctx, BulkOptions{})
+			Expect(err).To(MatchError(context.Canceled))
+		})
+	})
+})
@@ -0,0 +1,218 @@
+package cloud_foundry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIClient sends requests to the Cloud Foundry Cloud Controller API.
+type APIClient struct {
+	// Endpoint is the base URL of the Cloud Controller API, e.g. `https://api.sys.example.com`.
+	Endpoint string
+	// Tokens provides the OAuth access token sent in the Authorization header of each request. No header is sent
+	// when it is nil.
+	Tokens TokenSource
+	// HTTPClient is the client used to send the requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// MaxRetries is the number of times a request is retried when the API answers with a 429 or 5xx status code.
+	MaxRetries int
+	// RetryBackoff is the time to wait before the first retry. It doubles on each subsequent retry, unless the
+	// response includes a Retry-After header.
+	RetryBackoff time.Duration
+}
+
+const (
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 500 * time.Millisecond
+)
+
+// NewAPIClient returns a client for the Cloud Controller API at endpoint that authenticates with token.
+// The `bearer` prefix used by the CF CLI is removed from the token when present.
+func NewAPIClient(endpoint, token string) *APIClient {
+	return &APIClient{
+		Endpoint:     strings.TrimSuffix(endpoint, "/"),
+		Tokens:       StaticToken(token),
+		MaxRetries:   defaultMaxRetries,
+		RetryBackoff: defaultRetryBackoff,
+	}
+}
+
+func trimBearer(token string) string {
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		return token[7:]
+	}
+	return token
+}
+
+// APIError is returned when the Cloud Controller API answers with an unsuccessful status code.
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Errors contains the errors reported in the body of the response.
+	Errors []APIErrorDetail `json:"errors"`
+}
+
+type APIErrorDetail struct {
+	Code   int    `json:"code"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: unexpected status code %d", e.Method, e.URL, e.StatusCode)
+	for _, d := range e.Errors {
+		msg += fmt.Sprintf(": %s: %s", d.Title, d.Detail)
+	}
+	return msg
+}
+
+// resourceURL returns the absolute URL for path. Absolute URLs, like the ones in the pagination links, are
+// returned as they are.
+func (c *APIClient) resourceURL(path string, query url.Values) string {
+	u := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		u = c.Endpoint + path
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// getRaw sends a GET request to path and returns the body of the response. Requests that fail with a 429 or
+// 5xx status code are retried with an exponential backoff. A request rejected with a 401 status code is sent once
+// more with a new token when the token source can discard the rejected one.
+func (c *APIClient) getRaw(ctx context.Context, path string, query url.Values) ([]byte, error) {
+	u := c.resourceURL(path, query)
+	backoff := c.RetryBackoff
+	reauthenticated := false
+	for attempt := 0; ; attempt++ {
+		body, retryAfter, err := c.doGet(ctx, u)
+		if inv, ok := c.Tokens.(invalidator); ok && !reauthenticated && isUnauthorized(err) {
+			inv.Invalidate()
+			reauthenticated = true
+			attempt--
+			continue
+		}
+		if err == nil || attempt >= c.MaxRetries || !isRetryable(err) {
+			return body, err
+		}
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *APIClient) doGet(ctx context.Context, u string) ([]byte, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.Tokens != nil {
+		token, err := c.Tokens.Token(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "bearer "+token)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GET %s: error reading response: %w", u, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{Method: http.MethodGet, URL: u, StatusCode: resp.StatusCode}
+		// The body is decoded on a best effort basis to provide more details about the failure.
+		_ = json.Unmarshal(body, apiErr)
+		return nil, parseRetryAfter(resp.Header.Get("Retry-After")), apiErr
+	}
+	return body, 0, nil
+}
+
+func isRetryable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+}
+
+func isUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
+
+// parseRetryAfter returns the delay in a Retry-After header expressed in seconds.
+func parseRetryAfter(v string) time.Duration {
+	seconds, err := strconv.Atoi(v)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// get sends a GET request to path and decodes the JSON response into out.
+func (c *APIClient) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	body, err := c.getRaw(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("GET %s: error decoding response: %w", c.resourceURL(path, query), err)
+	}
+	return nil
+}
+
+// page is a page of resources in a v3 list response.
+type page[T any] struct {
+	Pagination struct {
+		Next *struct {
+			Href string `json:"href"`
+		} `json:"next"`
+	} `json:"pagination"`
+	Resources []T `json:"resources"`
+}
+
+// listAll retrieves the resources in all the pages of a v3 list endpoint.
+func listAll[T any](ctx context.Context, c *APIClient, path string, query url.Values) ([]T, error) {
+	resources := []T{}
+	for path != "" {
+		p := page[T]{}
+		if err := c.get(ctx, path, query, &p); err != nil {
+			return nil, err
+		}
+		resources = append(resources, p.Resources...)
+		path, query = "", nil
+		if p.Pagination.Next != nil {
+			path = p.Pagination.Next.Href
+		}
+	}
+	return resources, nil
+}
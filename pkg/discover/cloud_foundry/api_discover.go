@@ -0,0 +1,264 @@
+package cloud_foundry
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// resource captures the fields shared by the v3 resources used during the discovery.
+type resource struct {
+	GUID string `json:"guid"`
+	Name string `json:"name"`
+}
+
+type credentialBinding struct {
+	GUID          string `json:"guid"`
+	Name          string `json:"name"`
+	Relationships struct {
+		ServiceInstance struct {
+			Data struct {
+				GUID string `json:"guid"`
+			} `json:"data"`
+		} `json:"service_instance"`
+	} `json:"relationships"`
+}
+
+type credentialBindingDetails struct {
+	Credentials map[string]interface{} `json:"credentials"`
+}
+
+// APIVersion is a version of the Cloud Controller API.
+type APIVersion string
+
+const (
+	APIVersion2 APIVersion = "v2"
+	APIVersion3 APIVersion = "v3"
+)
+
+// APIDiscoverer discovers the applications deployed in a Cloud Foundry foundation using the Cloud Controller API.
+type APIDiscoverer struct {
+	Client *APIClient
+	// IncludeCredentials retrieves the credentials of the service bindings of each application. The credentials
+	// are sensitive and are not retrieved by default.
+	IncludeCredentials bool
+	// Version is the version of the API used to discover the applications. It is detected with DetectAPIVersion
+	// on the first discovery when empty.
+	Version APIVersion
+
+	mu sync.Mutex
+	// names caches the names of the v2 resources shared by many applications, like stacks and service instances, by URL.
+	names map[string]string
+	// domains caches the v2 domains of the routes by URL, which ends with their GUID.
+	domains map[string]v2Domain
+}
+
+// NewAPIDiscoverer returns a discoverer that uses client to query the Cloud Controller API.
+func NewAPIDiscoverer(client *APIClient) *APIDiscoverer {
+	return &APIDiscoverer{Client: client}
+}
+
+// DetectAPIVersion returns the most recent version of the API supported by the Cloud Controller at the endpoint of
+// client that can be used to discover the applications. The v3 API is used when the root endpoint links to it, and
+// foundations that only link to the v2 API, or have no root endpoint and only answer to `/v2/info`, use the v2 API.
+func DetectAPIVersion(ctx context.Context, client *APIClient) (APIVersion, error) {
+	root := struct {
+		Links map[string]interface{} `json:"links"`
+	}{}
+	if err := client.get(ctx, "/", nil, &root); err == nil {
+		if root.Links["cloud_controller_v3"] != nil {
+			return APIVersion3, nil
+		}
+		if root.Links["cloud_controller_v2"] != nil {
+			return APIVersion2, nil
+		}
+	}
+	if err := client.get(ctx, "/v2/info", nil, &struct{}{}); err != nil {
+		return "", fmt.Errorf("error detecting the version of the API: %w", err)
+	}
+	return APIVersion2, nil
+}
+
+// detectVersion sets the version of the API when it is not set yet.
+func (d *APIDiscoverer) detectVersion(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.Version != "" {
+		return nil
+	}
+	v, err := DetectAPIVersion(ctx, d.Client)
+	if err != nil {
+		return err
+	}
+	d.Version = v
+	return nil
+}
+
+// DiscoverSpace discovers all the applications in the space of the organization.
+func (d *APIDiscoverer) DiscoverSpace(ctx context.Context, org, space string) ([]Application, error) {
+	if err := d.detectVersion(ctx); err != nil {
+		return nil, err
+	}
+	spaceGUID, err := d.findSpace(ctx, org, space)
+	if err != nil {
+		return nil, err
+	}
+	apps, err := d.listApps(ctx, spaceGUID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the applications in space %s: %w", space, err)
+	}
+	result := make([]Application, 0, len(apps))
+	for _, app := range apps {
+		a, err := d.DiscoverApp(ctx, app.GUID, org, space)
+		if err != nil {
+			return nil, fmt.Errorf("application %q: %w", app.Name, err)
+		}
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+func (d *APIDiscoverer) findSpace(ctx context.Context, org, space string) (string, error) {
+	orgs, err := d.listOrganizations(ctx, []string{org})
+	if err != nil {
+		return "", fmt.Errorf("error retrieving organization %s: %w", org, err)
+	}
+	if len(orgs) == 0 {
+		return "", fmt.Errorf("organization %s not found", org)
+	}
+	spaces, err := d.listSpacesOf(ctx, orgs[0].GUID, space)
+	if err != nil {
+		return "", fmt.Errorf("error retrieving space %s: %w", space, err)
+	}
+	if len(spaces) == 0 {
+		return "", fmt.Errorf("space %s not found in organization %s", space, org)
+	}
+	return spaces[0].GUID, nil
+}
+
+// listOrganizations lists the organizations with the given names, or all the organizations when names is empty.
+func (d *APIDiscoverer) listOrganizations(ctx context.Context, names []string) ([]resource, error) {
+	if d.Version == APIVersion2 {
+		return d.listOrganizationsV2(ctx, names)
+	}
+	query := url.Values{}
+	if len(names) > 0 {
+		query.Set("names", strings.Join(names, ","))
+	}
+	return listAll[resource](ctx, d.Client, "/v3/organizations", query)
+}
+
+// listSpacesOf lists the spaces of the organization identified by orgGUID, or only the one with the given name
+// when name is not empty.
+func (d *APIDiscoverer) listSpacesOf(ctx context.Context, orgGUID, name string) ([]resource, error) {
+	if d.Version == APIVersion2 {
+		return d.listSpacesOfV2(ctx, orgGUID, name)
+	}
+	query := url.Values{"organization_guids": {orgGUID}}
+	if name != "" {
+		query.Set("names", name)
+	}
+	return listAll[resource](ctx, d.Client, "/v3/spaces", query)
+}
+
+// listApps lists the applications in the space identified by spaceGUID.
+func (d *APIDiscoverer) listApps(ctx context.Context, spaceGUID string) ([]resource, error) {
+	if d.Version == APIVersion2 {
+		return d.listAppsV2(ctx, spaceGUID)
+	}
+	return listAll[resource](ctx, d.Client, "/v3/apps", url.Values{"space_guids": {spaceGUID}})
+}
+
+// DiscoverApp discovers the application identified by guid, which is deployed in the space of the organization.
+func (d *APIDiscoverer) DiscoverApp(ctx context.Context, guid, org, space string) (Application, error) {
+	if err := d.detectVersion(ctx); err != nil {
+		return Application{}, err
+	}
+	if d.Version == APIVersion2 {
+		return d.discoverAppV2(ctx, guid, org, space)
+	}
+	return d.discoverAppV3(ctx, guid, org, space)
+}
+
+// discoverAppV3 builds the application from the manifest generated by the Cloud Controller, enriched with the
+// information of its service bindings.
+func (d *APIDiscoverer) discoverAppV3(ctx context.Context, guid, org, space string) (Application, error) {
+	body, err := d.Client.getRaw(ctx, "/v3/apps/"+guid+"/manifest", nil)
+	if err != nil {
+		return Application{}, fmt.Errorf("error retrieving the manifest: %w", err)
+	}
+	m := Manifest{}
+	if err := yaml.Unmarshal(body, &m); err != nil {
+		return Application{}, fmt.Errorf("error unmarshalling the manifest: %w", err)
+	}
+	if len(m.Applications) == 0 || m.Applications[0] == nil {
+		return Application{}, fmt.Errorf("the manifest contains no applications")
+	}
+	app, err := Discover(*m.Applications[0], m.Version, space)
+	if err != nil {
+		return Application{}, err
+	}
+	app.Metadata.Organization = org
+	if err := d.discoverBindings(ctx, guid, &app); err != nil {
+		return Application{}, err
+	}
+	return app, nil
+}
+
+// discoverBindings completes the services of the application with the binding names and, when requested,
+// the credentials of its service credential bindings.
+func (d *APIDiscoverer) discoverBindings(ctx context.Context, guid string, app *Application) error {
+	bindings, err := listAll[credentialBinding](ctx, d.Client, "/v3/service_credential_bindings", url.Values{"app_guids": {guid}, "type": {"app"}})
+	if err != nil {
+		return fmt.Errorf("error retrieving the service bindings: %w", err)
+	}
+	if len(bindings) == 0 {
+		return nil
+	}
+	guids := make([]string, 0, len(bindings))
+	for _, b := range bindings {
+		guids = append(guids, b.Relationships.ServiceInstance.Data.GUID)
+	}
+	serviceInstances, err := listAll[resource](ctx, d.Client, "/v3/service_instances", url.Values{"guids": {strings.Join(guids, ",")}})
+	if err != nil {
+		return fmt.Errorf("error retrieving the service instances: %w", err)
+	}
+	instances := map[string]string{}
+	for _, si := range serviceInstances {
+		instances[si.GUID] = si.Name
+	}
+
+	for _, b := range bindings {
+		name := instances[b.Relationships.ServiceInstance.Data.GUID]
+		i := app.Services.index(name)
+		if i < 0 {
+			app.Services = append(app.Services, ServiceSpec{Name: name})
+			i = len(app.Services) - 1
+		}
+		if app.Services[i].BindingName == "" {
+			app.Services[i].BindingName = b.Name
+		}
+		if !d.IncludeCredentials {
+			continue
+		}
+		details := credentialBindingDetails{}
+		if err := d.Client.get(ctx, "/v3/service_credential_bindings/"+b.GUID+"/details", nil, &details); err != nil {
+			return fmt.Errorf("error retrieving the credentials of service %s: %w", name, err)
+		}
+		app.Services[i].Credentials = details.Credentials
+	}
+	return nil
+}
+
+func (s Services) index(name string) int {
+	for i, svc := range s {
+		if svc.Name == name {
+			return i
+		}
+	}
+	return -1
+}
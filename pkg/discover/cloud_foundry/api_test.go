@@ -0,0 +1,218 @@
+package cloud_foundry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeCloudController is a stand-in of the Cloud Controller API that serves canned responses.
+type fakeCloudController struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses map[string][]fakeResponse
+	requests  []*http.Request
+}
+
+type fakeResponse struct {
+	status int
+	body   string
+}
+
+// newFakeCloudController returns a fake whose root endpoint links to the v3 API.
+func newFakeCloudController() *fakeCloudController {
+	f := &fakeCloudController{responses: map[string][]fakeResponse{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	DeferCleanup(f.Close)
+	f.handle("/", nil, http.StatusOK, map[string]interface{}{"links": map[string]interface{}{
+		"cloud_controller_v2": map[string]interface{}{"href": f.URL + "/v2"},
+		"cloud_controller_v3": map[string]interface{}{"href": f.URL + "/v3"},
+	}})
+	return f
+}
+
+func requestKey(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+// handle registers the responses for the request to path with query. When several responses are registered
+// they are returned in order, and the last one is repeated.
+func (f *fakeCloudController) handle(path string, query url.Values, status int, body interface{}) {
+	b, ok := body.(string)
+	if !ok {
+		j, err := json.Marshal(body)
+		Expect(err).NotTo(HaveOccurred())
+		b = string(j)
+	}
+	key := requestKey(path, query)
+	f.responses[key] = append(f.responses[key], fakeResponse{status: status, body: b})
+}
+
+func (f *fakeCloudController) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r)
+	key := requestKey(r.URL.Path, r.URL.Query())
+	responses, ok := f.responses[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[{"code":10010,"title":"CF-ResourceNotFound","detail":"` + key + ` not found"}]}`))
+		return
+	}
+	resp := responses[0]
+	if len(responses) > 1 {
+		f.responses[key] = responses[1:]
+	}
+	w.WriteHeader(resp.status)
+	_, _ = w.Write([]byte(resp.body))
+}
+
+// list returns the body of a v3 list response with the given resources and link to the next page.
+func list(next string, resources ...interface{}) map[string]interface{} {
+	pagination := map[string]interface{}{"next": nil}
+	if next != "" {
+		pagination["next"] = map[string]interface{}{"href": next}
+	}
+	if resources == nil {
+		resources = []interface{}{}
+	}
+	return map[string]interface{}{"pagination": pagination, "resources": resources}
+}
+
+func named(guid, name string) map[string]interface{} {
+	return map[string]interface{}{"guid": guid, "name": name}
+}
+
+func binding(guid, name, instanceGUID string) map[string]interface{} {
+	return map[string]interface{}{
+		"guid": guid,
+		"name": name,
+		"relationships": map[string]interface{}{
+			"service_instance": map[string]interface{}{"data": map[string]interface{}{"guid": instanceGUID}},
+		},
+	}
+}
+
+// registerSpace registers the responses to resolve the space guid of the org and space names.
+func (f *fakeCloudController) registerSpace(orgGUID, org, spaceGUID, space string) {
+	f.handle("/v3/organizations", url.Values{"names": {org}}, http.StatusOK, list("", named(orgGUID, org)))
+	f.handle("/v3/spaces", url.Values{"names": {space}, "organization_guids": {orgGUID}}, http.StatusOK, list("", named(spaceGUID, space)))
+}
+
+// registerApp registers the responses to discover an application with the given manifest and no service bindings.
+func (f *fakeCloudController) registerApp(guid, manifest string) {
+	f.handle("/v3/apps/"+guid+"/manifest", nil, http.StatusOK, manifest)
+	f.handle("/v3/service_credential_bindings", url.Values{"app_guids": {guid}, "type": {"app"}}, http.StatusOK, list(""))
+}
+
+var _ = Describe("API discovery", func() {
+	var (
+		cc         *fakeCloudController
+		discoverer *APIDiscoverer
+		ctx        context.Context
+	)
+
+	BeforeEach(func() {
+		cc = newFakeCloudController()
+		discoverer = NewAPIDiscoverer(NewAPIClient(cc.URL, "bearer secret-token"))
+		ctx = context.Background()
+	})
+
+	When("discovering the applications in a space", func() {
+		BeforeEach(func() {
+			cc.registerSpace("org-guid", "my-org", "space-guid", "dev")
+			cc.handle("/v3/apps", url.Values{"space_guids": {"space-guid"}}, http.StatusOK, list(cc.URL+"/v3/apps?page=2&space_guids=space-guid", named("app-1", "foo")))
+			cc.handle("/v3/apps", url.Values{"space_guids": {"space-guid"}, "page": {"2"}}, http.StatusOK, list("", named("app-2", "bar")))
+			cc.registerApp("app-1", `applications:
+- name: foo
+  env:
+    LOG_LEVEL: debug
+  routes:
+  - route: foo.example.com
+    protocol: http2
+  services:
+  - db
+  processes:
+  - type: web
+    instances: 2
+    memory: 512M
+  sidecars:
+  - name: proxy
+    process_types: [web]
+    command: ./proxy
+`)
+			cc.handle("/v3/apps/app-2/manifest", nil, http.StatusOK, "applications:\n- name: bar\n  no-route: true\n")
+			cc.handle("/v3/service_credential_bindings", url.Values{"app_guids": {"app-2"}, "type": {"app"}}, http.StatusOK,
+				list("", binding("binding-1", "my-db", "si-1"), binding("binding-2", "", "si-2")))
+			cc.handle("/v3/service_instances", url.Values{"guids": {"si-1,si-2"}}, http.StatusOK, list("", named("si-1", "db"), named("si-2", "cache")))
+			cc.handle("/v3/service_credential_bindings/binding-1/details", nil, http.StatusOK, map[string]interface{}{"credentials": map[string]interface{}{"password": "s3cr3t"}})
+			cc.handle("/v3/service_credential_bindings/binding-2/details", nil, http.StatusOK, map[string]interface{}{"credentials": map[string]interface{}{"uri": "redis://cache"}})
+		})
+
+		It("builds the applications from all the pages", func() {
+			apps, err := discoverer.DiscoverSpace(ctx, "my-org", "dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apps).To(HaveLen(2))
+
+			Expect(apps[0].Metadata).To(Equal(Metadata{Name: "foo", Space: "dev", Organization: "my-org", Version: "1"}))
+			Expect(apps[0].Env).To(Equal(map[string]string{"LOG_LEVEL": "debug"}))
+			Expect(apps[0].Routes.Routes).To(Equal(Routes{{Route: "foo.example.com", Protocol: HTTP2RouteProtocol}}))
+			Expect(apps[0].Services).To(Equal(Services{{Name: "db"}}))
+			Expect(apps[0].Processes).To(HaveLen(1))
+			Expect(apps[0].Processes[0].Instances).To(Equal(2))
+			Expect(apps[0].Sidecars).To(Equal(Sidecars{{Name: "proxy", ProcessTypes: []ProcessType{Web}, Command: "./proxy"}}))
+
+			Expect(apps[1].Metadata.Name).To(Equal("bar"))
+			Expect(apps[1].Routes).To(Equal(RouteSpec{NoRoute: true}))
+			Expect(apps[1].Services).To(Equal(Services{{Name: "db", BindingName: "my-db"}, {Name: "cache"}}))
+		})
+
+		It("retrieves the credentials when requested", func() {
+			discoverer.IncludeCredentials = true
+			apps, err := discoverer.DiscoverSpace(ctx, "my-org", "dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apps[1].Services).To(Equal(Services{
+				{Name: "db", BindingName: "my-db", Credentials: map[string]interface{}{"password": "s3cr3t"}},
+				{Name: "cache", Credentials: map[string]interface{}{"uri": "redis://cache"}},
+			}))
+		})
+
+		It("authenticates every request with the token", func() {
+			_, err := discoverer.DiscoverSpace(ctx, "my-org", "dev")
+			Expect(err).NotTo(HaveOccurred())
+			for _, r := range cc.requests {
+				Expect(r.Header.Get("Authorization")).To(Equal("bearer secret-token"))
+			}
+		})
+	})
+
+	When("the space does not exist", func() {
+		It("returns an error", func() {
+			cc.handle("/v3/organizations", url.Values{"names": {"my-org"}}, http.StatusOK, list("", named("org-guid", "my-org")))
+			cc.handle("/v3/spaces", url.Values{"names": {"prod"}, "organization_guids": {"org-guid"}}, http.StatusOK, list(""))
+			_, err := discoverer.DiscoverSpace(ctx, "my-org", "prod")
+			Expect(err).To(MatchError("space prod not found in organization my-org"))
+		})
+	})
+
+	When("the API returns an error", func() {
+		It("reports the status code and the error details", func() {
+			cc.handle("/v3/organizations", url.Values{"names": {"my-org"}}, http.StatusUnauthorized, `{"errors":[{"code":1000,"title":"CF-InvalidAuthToken","detail":"Invalid Auth Token"}]}`)
+			_, err := discoverer.DiscoverSpace(ctx, "my-org", "dev")
+			var apiErr *APIError
+			Expect(errors.As(err, &apiErr)).To(BeTrue())
+			Expect(apiErr.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(err).To(MatchError(ContainSubstring("CF-InvalidAuthToken: Invalid Auth Token")))
+		})
+	})
+})
@@ -0,0 +1,279 @@
+package cloud_foundry
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// v2Resource is a resource in a v2 API response, which splits the fields of the resource between its metadata
+// and its entity.
+type v2Resource[T any] struct {
+	Metadata struct {
+		GUID string `json:"guid"`
+	} `json:"metadata"`
+	Entity T `json:"entity"`
+}
+
+// v2Page is a page of resources in a v2 list response.
+type v2Page[T any] struct {
+	NextURL   string          `json:"next_url"`
+	Resources []v2Resource[T] `json:"resources"`
+}
+
+type v2Named struct {
+	Name string `json:"name"`
+}
+
+type v2App struct {
+	Name                    string                 `json:"name"`
+	Memory                  int                    `json:"memory"`
+	DiskQuota               int                    `json:"disk_quota"`
+	Instances               uint                   `json:"instances"`
+	Command                 string                 `json:"command"`
+	Buildpack               string                 `json:"buildpack"`
+	HealthCheckType         string                 `json:"health_check_type"`
+	HealthCheckHTTPEndpoint string                 `json:"health_check_http_endpoint"`
+	HealthCheckTimeout      uint                   `json:"health_check_timeout"`
+	EnvironmentJSON         map[string]interface{} `json:"environment_json"`
+	DockerImage             string                 `json:"docker_image"`
+	DockerCredentials       *struct {
+		Username string `json:"username"`
+	} `json:"docker_credentials"`
+	StackURL string `json:"stack_url"`
+}
+
+type v2Route struct {
+	Host      string `json:"host"`
+	Path      string `json:"path"`
+	Port      int    `json:"port"`
+	DomainURL string `json:"domain_url"`
+}
+
+type v2Domain struct {
+	Name            string `json:"name"`
+	RouterGroupType string `json:"router_group_type"`
+}
+
+type v2ServiceBinding struct {
+	Name               string                 `json:"name"`
+	Credentials        map[string]interface{} `json:"credentials"`
+	ServiceInstanceURL string                 `json:"service_instance_url"`
+}
+
+// listAllV2 retrieves the resources in all the pages of a v2 list endpoint.
+func listAllV2[T any](ctx context.Context, c *APIClient, path string, query url.Values) ([]v2Resource[T], error) {
+	resources := []v2Resource[T]{}
+	for path != "" {
+		p := v2Page[T]{}
+		if err := c.get(ctx, path, query, &p); err != nil {
+			return nil, err
+		}
+		resources = append(resources, p.Resources...)
+		// The link to the next page is relative to the endpoint and includes the query.
+		path, query = p.NextURL, nil
+	}
+	return resources, nil
+}
+
+// namedResources converts the v2 resources to the fields shared with the v3 resources.
+func namedResources(resources []v2Resource[v2Named]) []resource {
+	result := make([]resource, 0, len(resources))
+	for _, r := range resources {
+		result = append(result, resource{GUID: r.Metadata.GUID, Name: r.Entity.Name})
+	}
+	return result
+}
+
+func (d *APIDiscoverer) listOrganizationsV2(ctx context.Context, names []string) ([]resource, error) {
+	query := url.Values{}
+	if len(names) > 0 {
+		query.Set("q", "name IN "+strings.Join(names, ","))
+	}
+	orgs, err := listAllV2[v2Named](ctx, d.Client, "/v2/organizations", query)
+	return namedResources(orgs), err
+}
+
+func (d *APIDiscoverer) listSpacesOfV2(ctx context.Context, orgGUID, name string) ([]resource, error) {
+	query := url.Values{"q": {"organization_guid:" + orgGUID}}
+	if name != "" {
+		query.Add("q", "name:"+name)
+	}
+	spaces, err := listAllV2[v2Named](ctx, d.Client, "/v2/spaces", query)
+	return namedResources(spaces), err
+}
+
+func (d *APIDiscoverer) listAppsV2(ctx context.Context, spaceGUID string) ([]resource, error) {
+	apps, err := listAllV2[v2Named](ctx, d.Client, "/v2/spaces/"+spaceGUID+"/apps", nil)
+	return namedResources(apps), err
+}
+
+// discoverAppV2 builds the manifest of the application from its v2 resource, routes and service bindings, and
+// discovers it like the manifests generated by the v3 API, which have a single web process.
+func (d *APIDiscoverer) discoverAppV2(ctx context.Context, guid, org, space string) (Application, error) {
+	r := v2Resource[v2App]{}
+	if err := d.Client.get(ctx, "/v2/apps/"+guid, nil, &r); err != nil {
+		return Application{}, fmt.Errorf("error retrieving the application: %w", err)
+	}
+	a := r.Entity
+	process := AppManifestProcess{
+		Type:                    WebAppProcessType,
+		Command:                 a.Command,
+		HealthCheckType:         AppHealthCheckType(a.HealthCheckType),
+		HealthCheckHTTPEndpoint: a.HealthCheckHTTPEndpoint,
+		Instances:               &a.Instances,
+		Timeout:                 a.HealthCheckTimeout,
+	}
+	if a.HealthCheckType == "none" {
+		// `none` is the deprecated name of the process health check.
+		process.HealthCheckType = Process
+	}
+	if a.Memory > 0 {
+		process.Memory = strconv.Itoa(a.Memory) + "M"
+	}
+	if a.DiskQuota > 0 {
+		process.DiskQuota = strconv.Itoa(a.DiskQuota) + "M"
+	}
+	m := AppManifest{
+		Name:               a.Name,
+		Env:                a.EnvironmentJSON,
+		Processes:          &AppManifestProcesses{process},
+		AppManifestProcess: AppManifestProcess{Instances: process.Instances, Timeout: process.Timeout},
+	}
+	if a.Buildpack != "" {
+		m.Buildpacks = []string{a.Buildpack}
+	}
+	if a.DockerImage != "" {
+		m.Docker = &AppManifestDocker{Image: a.DockerImage}
+		if a.DockerCredentials != nil {
+			m.Docker.Username = a.DockerCredentials.Username
+		}
+	}
+	if a.StackURL != "" {
+		stack, err := d.nameV2(ctx, a.StackURL)
+		if err != nil {
+			return Application{}, fmt.Errorf("error retrieving the stack: %w", err)
+		}
+		m.Stack = stack
+	}
+	routes, err := d.routesV2(ctx, guid)
+	if err != nil {
+		return Application{}, err
+	}
+	if len(routes) == 0 {
+		m.NoRoute = true
+	} else {
+		m.Routes = &routes
+	}
+	services, credentials, err := d.servicesV2(ctx, guid)
+	if err != nil {
+		return Application{}, err
+	}
+	if len(services) > 0 {
+		m.Services = &services
+	}
+
+	app, err := Discover(m, "", space)
+	if err != nil {
+		return Application{}, err
+	}
+	app.Metadata.Organization = org
+	if d.IncludeCredentials {
+		for i := range app.Services {
+			app.Services[i].Credentials = credentials[i]
+		}
+	}
+	return app, nil
+}
+
+// routesV2 returns the routes mapped to the application in the format of the manifest.
+func (d *APIDiscoverer) routesV2(ctx context.Context, guid string) (AppManifestRoutes, error) {
+	resources, err := listAllV2[v2Route](ctx, d.Client, "/v2/apps/"+guid+"/routes", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving the routes: %w", err)
+	}
+	routes := AppManifestRoutes{}
+	for _, r := range resources {
+		domain, err := d.domainV2(ctx, r.Entity.DomainURL)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving the domain of the routes: %w", err)
+		}
+		route := AppManifestRoute{Route: domain.Name}
+		if r.Entity.Host != "" {
+			route.Route = r.Entity.Host + "." + route.Route
+		}
+		if domain.RouterGroupType == "tcp" {
+			route.Route += ":" + strconv.Itoa(r.Entity.Port)
+			route.Protocol = TCP
+		}
+		route.Route += r.Entity.Path
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// domainV2 returns the v2 domain at path. Like the names, the domains are cached since the routes of most
+// applications share the same few domains.
+func (d *APIDiscoverer) domainV2(ctx context.Context, path string) (v2Domain, error) {
+	d.mu.Lock()
+	domain, ok := d.domains[path]
+	d.mu.Unlock()
+	if ok {
+		return domain, nil
+	}
+	r := v2Resource[v2Domain]{}
+	if err := d.Client.get(ctx, path, nil, &r); err != nil {
+		return v2Domain{}, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.domains == nil {
+		d.domains = map[string]v2Domain{}
+	}
+	d.domains[path] = r.Entity
+	return r.Entity, nil
+}
+
+// servicesV2 returns the services bound to the application in the format of the manifest, and the credentials of
+// each binding. The v2 API includes the credentials in the bindings, so they are dropped unless requested.
+func (d *APIDiscoverer) servicesV2(ctx context.Context, guid string) (AppManifestServices, []map[string]interface{}, error) {
+	bindings, err := listAllV2[v2ServiceBinding](ctx, d.Client, "/v2/apps/"+guid+"/service_bindings", nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error retrieving the service bindings: %w", err)
+	}
+	services := AppManifestServices{}
+	credentials := []map[string]interface{}{}
+	for _, b := range bindings {
+		name, err := d.nameV2(ctx, b.Entity.ServiceInstanceURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error retrieving the service instances: %w", err)
+		}
+		services = append(services, AppManifestService{Name: name, BindingName: b.Entity.Name})
+		credentials = append(credentials, b.Entity.Credentials)
+	}
+	return services, credentials, nil
+}
+
+// nameV2 returns the name of the v2 resource at path. The names are cached since many applications share the same
+// stacks and service instances.
+func (d *APIDiscoverer) nameV2(ctx context.Context, path string) (string, error) {
+	d.mu.Lock()
+	name, ok := d.names[path]
+	d.mu.Unlock()
+	if ok {
+		return name, nil
+	}
+	r := v2Resource[v2Named]{}
+	if err := d.Client.get(ctx, path, nil, &r); err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.names == nil {
+		d.names = map[string]string{}
+	}
+	d.names[path] = r.Entity.Name
+	return r.Entity.Name, nil
+}
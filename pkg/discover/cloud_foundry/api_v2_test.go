@@ -0,0 +1,174 @@
+package cloud_foundry
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// v2List returns the body of a v2 list response with the given resources and link to the next page.
+func v2List(next string, resources ...interface{}) map[string]interface{} {
+	body := map[string]interface{}{"next_url": nil, "resources": resources}
+	if next != "" {
+		body["next_url"] = next
+	}
+	if resources == nil {
+		body["resources"] = []interface{}{}
+	}
+	return body
+}
+
+func v2Entity(guid string, entity map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"metadata": map[string]interface{}{"guid": guid}, "entity": entity}
+}
+
+var _ = Describe("API v2 discovery", func() {
+	var (
+		cc         *fakeCloudController
+		discoverer *APIDiscoverer
+		ctx        context.Context
+	)
+
+	BeforeEach(func() {
+		cc = newFakeCloudController()
+		discoverer = NewAPIDiscoverer(NewAPIClient(cc.URL, "secret-token"))
+		ctx = context.Background()
+	})
+
+	DescribeTable("detects the version of the API",
+		func(root, info interface{}, expected APIVersion) {
+			delete(cc.responses, "/")
+			if root != nil {
+				cc.handle("/", nil, http.StatusOK, root)
+			}
+			if info != nil {
+				cc.handle("/v2/info", nil, http.StatusOK, info)
+			}
+			Expect(DetectAPIVersion(ctx, discoverer.Client)).To(Equal(expected))
+		},
+		Entry("v3 when the root endpoint links to it",
+			map[string]interface{}{"links": map[string]interface{}{"cloud_controller_v3": map[string]interface{}{"href": "https://api.example.com/v3"}}},
+			nil, APIVersion3),
+		Entry("v2 when the root endpoint only links to v2",
+			map[string]interface{}{"links": map[string]interface{}{"cloud_controller_v2": map[string]interface{}{"href": "https://api.example.com/v2"}}},
+			nil, APIVersion2),
+		Entry("v2 when there is no root endpoint",
+			nil, map[string]interface{}{"api_version": "2.100.0"}, APIVersion2),
+	)
+
+	When("the foundation only supports the v2 API", func() {
+		BeforeEach(func() {
+			delete(cc.responses, "/")
+			cc.handle("/v2/info", nil, http.StatusOK, map[string]interface{}{"api_version": "2.100.0"})
+			cc.handle("/v2/organizations", url.Values{"q": {"name IN my-org"}}, http.StatusOK, v2List("", v2Entity("org-guid", map[string]interface{}{"name": "my-org"})))
+			cc.handle("/v2/spaces", url.Values{"q": {"organization_guid:org-guid", "name:dev"}}, http.StatusOK, v2List("", v2Entity("space-guid", map[string]interface{}{"name": "dev"})))
+			cc.handle("/v2/spaces/space-guid/apps", nil, http.StatusOK, v2List("/v2/spaces/space-guid/apps?page=2", v2Entity("app-1", map[string]interface{}{"name": "foo"})))
+			cc.handle("/v2/spaces/space-guid/apps", url.Values{"page": {"2"}}, http.StatusOK, v2List("", v2Entity("app-2", map[string]interface{}{"name": "bar"})))
+
+			cc.handle("/v2/apps/app-1", nil, http.StatusOK, v2Entity("app-1", map[string]interface{}{
+				"name":                       "foo",
+				"memory":                     512,
+				"disk_quota":                 1024,
+				"instances":                  2,
+				"command":                    "./start",
+				"buildpack":                  "java_buildpack",
+				"health_check_type":          "http",
+				"health_check_http_endpoint": "/health",
+				"health_check_timeout":       120,
+				"environment_json":           map[string]interface{}{"LOG_LEVEL": "debug", "WORKERS": 4},
+				"stack_url":                  "/v2/stacks/stack-guid",
+				"state":                      "STARTED",
+			}))
+			cc.handle("/v2/stacks/stack-guid", nil, http.StatusOK, v2Entity("stack-guid", map[string]interface{}{"name": "cflinuxfs4"}))
+			cc.handle("/v2/apps/app-1/routes", nil, http.StatusOK, v2List("",
+				v2Entity("route-1", map[string]interface{}{"host": "foo", "path": "/api", "domain_url": "/v2/shared_domains/http-domain"}),
+				v2Entity("route-2", map[string]interface{}{"host": "", "port": 1034, "domain_url": "/v2/shared_domains/tcp-domain"}),
+			))
+			cc.handle("/v2/shared_domains/http-domain", nil, http.StatusOK, v2Entity("http-domain", map[string]interface{}{"name": "apps.example.com"}))
+			cc.handle("/v2/shared_domains/tcp-domain", nil, http.StatusOK, v2Entity("tcp-domain", map[string]interface{}{"name": "tcp.example.com", "router_group_type": "tcp"}))
+			cc.handle("/v2/apps/app-1/service_bindings", nil, http.StatusOK, v2List("",
+				v2Entity("binding-1", map[string]interface{}{"name": "my-db", "credentials": map[string]interface{}{"password": "s3cr3t"}, "service_instance_url": "/v2/service_instances/si-1"}),
+				v2Entity("binding-2", map[string]interface{}{"name": nil, "credentials": map[string]interface{}{"uri": "redis://cache"}, "service_instance_url": "/v2/user_provided_service_instances/si-2"}),
+			))
+			cc.handle("/v2/service_instances/si-1", nil, http.StatusOK, v2Entity("si-1", map[string]interface{}{"name": "db"}))
+			cc.handle("/v2/user_provided_service_instances/si-2", nil, http.StatusOK, v2Entity("si-2", map[string]interface{}{"name": "cache"}))
+
+			cc.handle("/v2/apps/app-2", nil, http.StatusOK, v2Entity("app-2", map[string]interface{}{
+				"name":              "bar",
+				"memory":            256,
+				"instances":         1,
+				"health_check_type": "none",
+				"docker_image":      "registry.example.com/bar:1.0",
+				"docker_credentials": map[string]interface{}{
+					"username": "robot",
+					"password": "registry-s3cr3t",
+				},
+			}))
+			cc.handle("/v2/apps/app-2/routes", nil, http.StatusOK, v2List(""))
+			cc.handle("/v2/apps/app-2/service_bindings", nil, http.StatusOK, v2List(""))
+		})
+
+		It("builds the same applications as the v3 API", func() {
+			apps, err := discoverer.DiscoverSpace(ctx, "my-org", "dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(discoverer.Version).To(Equal(APIVersion2))
+			Expect(apps).To(HaveLen(2))
+
+			foo := apps[0]
+			Expect(foo.Metadata).To(Equal(Metadata{Name: "foo", Space: "dev", Organization: "my-org", Version: "1"}))
+			Expect(foo.Instances).To(Equal(2))
+			Expect(foo.Timeout).To(Equal(120))
+			Expect(foo.BuildPacks).To(Equal([]string{"java_buildpack"}))
+			Expect(foo.Stack).To(Equal("cflinuxfs4"))
+			Expect(foo.Env).To(Equal(map[string]string{"LOG_LEVEL": "debug", "WORKERS": "4"}))
+			Expect(foo.Routes.Routes).To(Equal(Routes{
+				{Route: "foo.apps.example.com/api"},
+				{Route: "tcp.example.com:1034", Protocol: TCPRouteProtocol},
+			}))
+			Expect(foo.Services).To(Equal(Services{{Name: "db", BindingName: "my-db"}, {Name: "cache"}}))
+			Expect(foo.Processes).To(HaveLen(1))
+			Expect(foo.Processes[0].Type).To(Equal(Web))
+			Expect(foo.Processes[0].Command).To(Equal("./start"))
+			Expect(foo.Processes[0].Memory).To(BeEquivalentTo("512M"))
+			Expect(foo.Processes[0].DiskQuota).To(BeEquivalentTo("1024M"))
+			Expect(foo.Processes[0].Instances).To(Equal(2))
+			Expect(foo.Processes[0].HealthCheck.Type).To(Equal(HTTPProbeType))
+			Expect(foo.Processes[0].HealthCheck.Endpoint).To(Equal("/health"))
+
+			bar := apps[1]
+			Expect(bar.Routes).To(Equal(RouteSpec{NoRoute: true}))
+			Expect(bar.Docker).To(Equal(Docker{Image: "registry.example.com/bar:1.0", Username: "robot"}))
+			Expect(bar.Processes[0].HealthCheck.Type).To(Equal(ProcessProbeType))
+		})
+
+		It("only keeps the credentials of the bindings when requested", func() {
+			discoverer.IncludeCredentials = true
+			apps, err := discoverer.DiscoverSpace(ctx, "my-org", "dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apps[0].Services).To(Equal(Services{
+				{Name: "db", BindingName: "my-db", Credentials: map[string]interface{}{"password": "s3cr3t"}},
+				{Name: "cache", Credentials: map[string]interface{}{"uri": "redis://cache"}},
+			}))
+		})
+
+		It("retrieves each domain once", func() {
+			delete(cc.responses, "/v2/apps/app-2/routes")
+			cc.handle("/v2/apps/app-2/routes", nil, http.StatusOK, v2List("",
+				v2Entity("route-3", map[string]interface{}{"host": "bar", "domain_url": "/v2/shared_domains/http-domain"}),
+			))
+			apps, err := discoverer.DiscoverSpace(ctx, "my-org", "dev")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apps[1].Routes.Routes).To(Equal(Routes{{Route: "bar.apps.example.com"}}))
+			domains := 0
+			for _, r := range cc.requests {
+				if r.URL.Path == "/v2/shared_domains/http-domain" {
+					domains++
+				}
+			}
+			Expect(domains).To(Equal(1))
+		})
+	})
+})
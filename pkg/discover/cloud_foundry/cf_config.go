@@ -0,0 +1,127 @@
+package cloud_foundry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cfHomeEnvVar is the environment variable used by the CF CLI to relocate the directory containing `.cf/config.json`.
+const cfHomeEnvVar = "CF_HOME"
+
+// CFConfig contains the login session stored by the CF CLI after `cf login` and `cf target`.
+type CFConfig struct {
+	// Target is the URL of the targeted Cloud Controller API.
+	Target string
+	// UAAEndpoint is the URL of the UAA server that issued the tokens.
+	UAAEndpoint string
+	// AccessToken and RefreshToken are the tokens of the logged-in user.
+	AccessToken  string
+	RefreshToken string
+	// UAAClientID and UAAClientSecret identify the OAuth client that requested the tokens.
+	UAAClientID     string
+	UAAClientSecret string
+	// SkipSSLValidation is true when the user logged in with `--skip-ssl-validation`.
+	SkipSSLValidation bool
+	// Organization and Space are the names of the targeted organization and space, if any.
+	Organization string
+	Space        string
+}
+
+// CFConfigPath returns the path to the configuration of the CF CLI, `$CF_HOME/.cf/config.json` or
+// `~/.cf/config.json` when CF_HOME is not set.
+func CFConfigPath() (string, error) {
+	home := os.Getenv(cfHomeEnvVar)
+	if home == "" {
+		var err error
+		if home, err = os.UserHomeDir(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(home, ".cf", "config.json"), nil
+}
+
+// ReadCFConfig reads the login session stored by the CF CLI in the configuration file at path.
+func ReadCFConfig(path string) (*CFConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the CF CLI configuration: %w", err)
+	}
+	raw := struct {
+		Target                string
+		UaaEndpoint           string
+		AuthorizationEndpoint string
+		AccessToken           string
+		RefreshToken          string
+		UAAOAuthClient        string
+		UAAOAuthClientSecret  string
+		SSLDisabled           bool
+		OrganizationFields    struct{ Name string }
+		SpaceFields           struct{ Name string }
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("error parsing the CF CLI configuration %s: %w", path, err)
+	}
+	if raw.Target == "" {
+		return nil, errors.New("the CF CLI is not targeting any API: run `cf login` first")
+	}
+	c := &CFConfig{
+		Target:            strings.TrimSuffix(raw.Target, "/"),
+		UAAEndpoint:       raw.UaaEndpoint,
+		AccessToken:       raw.AccessToken,
+		RefreshToken:      raw.RefreshToken,
+		UAAClientID:       raw.UAAOAuthClient,
+		UAAClientSecret:   raw.UAAOAuthClientSecret,
+		SkipSSLValidation: raw.SSLDisabled,
+		Organization:      raw.OrganizationFields.Name,
+		Space:             raw.SpaceFields.Name,
+	}
+	if c.UAAEndpoint == "" {
+		c.UAAEndpoint = raw.AuthorizationEndpoint
+	}
+	return c, nil
+}
+
+// TokenSource returns a TokenSource that starts with the stored access token and refreshes it with the stored refresh
+// token once it expires. The refreshed tokens are not written back to the configuration file.
+func (c *CFConfig) TokenSource(httpClient *http.Client) (TokenSource, error) {
+	if c.AccessToken == "" && c.RefreshToken == "" {
+		return nil, errors.New("the CF CLI is not logged in: run `cf login` first")
+	}
+	if c.RefreshToken == "" {
+		return StaticToken(c.AccessToken), nil
+	}
+	accessToken := trimBearer(c.AccessToken)
+	uaa := &UAAClient{Endpoint: c.UAAEndpoint, ClientID: c.UAAClientID, ClientSecret: c.UAAClientSecret, HTTPClient: httpClient}
+	return NewUAATokenSourceFromToken(uaa, &Token{
+		AccessToken:  accessToken,
+		RefreshToken: c.RefreshToken,
+		Expiry:       tokenExpiry(accessToken),
+	}), nil
+}
+
+// tokenExpiry returns the expiration time in the `exp` claim of a JWT access token. A zero time is returned when
+// the token cannot be decoded, in which case it is only refreshed after the API rejects it.
+func tokenExpiry(accessToken string) time.Time {
+	parts := strings.Split(accessToken, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	claims := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
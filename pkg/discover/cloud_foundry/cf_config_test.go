@@ -0,0 +1,102 @@
+package cloud_foundry
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// jwt returns an unsigned JWT whose payload expires at exp.
+func jwt(exp time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+	return "eyJhbGciOiJub25lIn0." + payload + ".signature"
+}
+
+var _ = Describe("CF CLI configuration", func() {
+	var (
+		cfHome string
+		uaa    *fakeUAA
+	)
+
+	writeConfig := func(accessToken string) {
+		Expect(os.MkdirAll(filepath.Join(cfHome, ".cf"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cfHome, ".cf", "config.json"), []byte(fmt.Sprintf(`{
+  "ConfigVersion": 3,
+  "Target": "https://api.sys.example.com/",
+  "AuthorizationEndpoint": "https://login.sys.example.com",
+  "UaaEndpoint": %q,
+  "AccessToken": "bearer %s",
+  "RefreshToken": "stored-refresh",
+  "UAAOAuthClient": "cf",
+  "UAAOAuthClientSecret": "",
+  "SSLDisabled": true,
+  "OrganizationFields": {"GUID": "org-guid", "Name": "my-org"},
+  "SpaceFields": {"GUID": "space-guid", "Name": "dev", "AllowSSH": true}
+}`, uaa.URL, accessToken)), 0o600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		cfHome = GinkgoT().TempDir()
+		GinkgoT().Setenv(cfHomeEnvVar, cfHome)
+		uaa = newFakeUAA(3600)
+	})
+
+	It("reads the target, the tokens and the targeted space", func() {
+		writeConfig("access")
+		path, err := CFConfigPath()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(path).To(Equal(filepath.Join(cfHome, ".cf", "config.json")))
+
+		config, err := ReadCFConfig(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*config).To(Equal(CFConfig{
+			Target:            "https://api.sys.example.com",
+			UAAEndpoint:       uaa.URL,
+			AccessToken:       "bearer access",
+			RefreshToken:      "stored-refresh",
+			UAAClientID:       "cf",
+			SkipSSLValidation: true,
+			Organization:      "my-org",
+			Space:             "dev",
+		}))
+	})
+
+	DescribeTable("uses the stored access token until it expires",
+		func(exp time.Time, expectRefresh bool) {
+			accessToken := jwt(exp)
+			writeConfig(accessToken)
+			path, _ := CFConfigPath()
+			config, err := ReadCFConfig(path)
+			Expect(err).NotTo(HaveOccurred())
+			source, err := config.TokenSource(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			token, err := source.Token(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			if expectRefresh {
+				Expect(token).To(Equal("access-1"))
+				Expect(uaa.grants).To(Equal([]url.Values{{"grant_type": {"refresh_token"}, "refresh_token": {"stored-refresh"}}}))
+			} else {
+				Expect(token).To(Equal(accessToken))
+				Expect(uaa.grants).To(BeEmpty())
+			}
+		},
+		Entry("with a valid token", time.Now().Add(time.Hour), false),
+		Entry("with an expired token", time.Now().Add(-time.Hour), true),
+	)
+
+	It("fails when the CF CLI is not logged in", func() {
+		Expect(os.MkdirAll(filepath.Join(cfHome, ".cf"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cfHome, ".cf", "config.json"), []byte(`{"ConfigVersion": 3}`), 0o600)).To(Succeed())
+		path, _ := CFConfigPath()
+		_, err := ReadCFConfig(path)
+		Expect(err).To(MatchError("the CF CLI is not targeting any API: run `cf login` first"))
+	})
+})
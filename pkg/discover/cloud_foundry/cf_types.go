@@ -37,18 +37,24 @@ type AppMetadata struct {
 	Annotations map[string]*string `json:"annotations"`
 }
 type AppManifest struct {
-	Name               string                `yaml:"name"`
-	Buildpacks         []string              `yaml:"buildpacks,omitempty"`
-	Docker             *AppManifestDocker    `yaml:"docker,omitempty"`
-	Env                map[string]string     `yaml:"env,omitempty"`
-	RandomRoute        bool                  `yaml:"random-route,omitempty"`
-	NoRoute            bool                  `yaml:"no-route,omitempty"`
-	Routes             *AppManifestRoutes    `yaml:"routes,omitempty"`
-	Services           *AppManifestServices  `yaml:"services,omitempty"`
-	Sidecars           *AppManifestSideCars  `yaml:"sidecars,omitempty"`
-	Processes          *AppManifestProcesses `yaml:"processes,omitempty"`
-	Stack              string                `yaml:"stack,omitempty"`
-	Metadata           *AppMetadata          `yaml:"metadata,omitempty"`
+	Name               string                 `yaml:"name"`
+	Buildpacks         []string               `yaml:"buildpacks,omitempty"`
+	Path               string                 `yaml:"path,omitempty"`
+	Docker             *AppManifestDocker     `yaml:"docker,omitempty"`
+	Env                map[string]interface{} `yaml:"env,omitempty"`
+	RandomRoute        bool                   `yaml:"random-route,omitempty"`
+	NoRoute            bool                   `yaml:"no-route,omitempty"`
+	Routes             *AppManifestRoutes     `yaml:"routes,omitempty"`
+	Host               string                 `yaml:"host,omitempty"`
+	Hosts              []string               `yaml:"hosts,omitempty"`
+	Domain             string                 `yaml:"domain,omitempty"`
+	Domains            []string               `yaml:"domains,omitempty"`
+	NoHostname         bool                   `yaml:"no-hostname,omitempty"`
+	Services           *AppManifestServices   `yaml:"services,omitempty"`
+	Sidecars           *AppManifestSideCars   `yaml:"sidecars,omitempty"`
+	Processes          *AppManifestProcesses  `yaml:"processes,omitempty"`
+	Stack              string                 `yaml:"stack,omitempty"`
+	Metadata           *AppMetadata           `yaml:"metadata,omitempty"`
 	AppManifestProcess `yaml:",inline"`
 }
 
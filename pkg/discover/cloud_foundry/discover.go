@@ -2,6 +2,8 @@ package cloud_foundry
 
 import (
 	"encoding/json"
+	"errors"
+	"slices"
 )
 
 func Discover(cfApp AppManifest, version, space string) (Application, error) {
@@ -18,8 +20,15 @@ func Discover(cfApp AppManifest, version, space string) (Application, error) {
 	if cfApp.Instances != nil {
 		instances = int(*cfApp.Instances)
 	}
+	env, envTypes, err := parseEnv(cfApp.Env)
+	if err != nil {
+		return Application{}, err
+	}
 	services := parseServices(cfApp.Services)
-	routeSpec := parseRouteSpec(cfApp.Routes, cfApp.RandomRoute, cfApp.NoRoute)
+	routeSpec, err := parseAppRouteSpec(cfApp)
+	if err != nil {
+		return Application{}, err
+	}
 	docker := parseDocker(cfApp.Docker)
 	sidecars := parseSidecars(cfApp.Sidecars)
 	processes, err := parseProcesses(cfApp)
@@ -33,7 +42,7 @@ func Discover(cfApp AppManifest, version, space string) (Application, error) {
 		annotations = cfApp.Metadata.Annotations
 	}
 
-	return Application{
+	app := Application{
 		Metadata: Metadata{
 			Version:     appVersion,
 			Name:        cfApp.Name,
@@ -44,14 +53,18 @@ func Discover(cfApp AppManifest, version, space string) (Application, error) {
 		Timeout:    timeout,
 		Instances:  instances,
 		BuildPacks: cfApp.Buildpacks,
-		Env:        cfApp.Env,
+		Path:       cfApp.Path,
+		Env:        env,
+		EnvTypes:   envTypes,
 		Stack:      cfApp.Stack,
 		Services:   services,
 		Routes:     routeSpec,
 		Docker:     docker,
 		Sidecars:   sidecars,
 		Processes:  processes,
-	}, nil
+	}
+	linkSidecars(&app)
+	return app, nil
 }
 
 func parseHealthCheck(cfType AppHealthCheckType, cfEndpoint string, cfInterval, cfTimeout uint) ProbeSpec {
@@ -136,22 +149,22 @@ func parseInlinedProcessSpec(cfApp AppManifest) (AppManifestProcess, error) {
 }
 
 func parseProcess(cfProcess AppManifestProcess) ProcessSpec {
-	memory := "1G"
+	memory := Quantity("1G")
 	if len(cfProcess.Memory) != 0 {
-		memory = cfProcess.Memory
+		memory = Quantity(cfProcess.Memory)
 	}
 	instances := 1
 	if cfProcess.Instances != nil {
 		instances = int(*cfProcess.Instances)
 	}
-	logRateLimit := "16K"
+	logRateLimit := Quantity("16K")
 	if len(cfProcess.LogRateLimitPerSecond) > 0 {
-		logRateLimit = cfProcess.LogRateLimitPerSecond
+		logRateLimit = Quantity(cfProcess.LogRateLimitPerSecond)
 	}
 	p := ProcessSpec{
 		Type:           ProcessType(cfProcess.Type),
 		Command:        cfProcess.Command,
-		DiskQuota:      cfProcess.DiskQuota,
+		DiskQuota:      Quantity(cfProcess.DiskQuota),
 		Memory:         memory,
 		HealthCheck:    parseHealthCheck(cfProcess.HealthCheckType, cfProcess.HealthCheckHTTPEndpoint, cfProcess.HealthCheckInterval, cfProcess.HealthCheckInvocationTimeout),
 		ReadinessCheck: parseReadinessHealthCheck(cfProcess.ReadinessHealthCheckType, cfProcess.ReadinessHealthCheckHttpEndpoint, cfProcess.ReadinessHealthCheckInterval, cfProcess.ReadinessHealthInvocationTimeout),
@@ -181,7 +194,7 @@ func parseSidecars(cfSidecars *AppManifestSideCars) Sidecars {
 			Name:         cfSidecar.Name,
 			Command:      cfSidecar.Command,
 			ProcessTypes: pt,
-			Memory:       cfSidecar.Memory,
+			Memory:       Quantity(cfSidecar.Memory),
 		}
 		sidecars = append(sidecars, s)
 	}
@@ -213,6 +226,79 @@ func parseServices(cfServices *AppManifestServices) Services {
 	return services
 }
 
+// parseAppRouteSpec returns the route specification of the application, translating the deprecated
+// route attributes into routes when the manifest uses them.
+func parseAppRouteSpec(cfApp AppManifest) (RouteSpec, error) {
+	if !hasLegacyRouteAttributes(cfApp) {
+		return parseRouteSpec(cfApp.Routes, cfApp.RandomRoute, cfApp.NoRoute), nil
+	}
+	if cfApp.Routes != nil {
+		return RouteSpec{}, errors.New("the routes field cannot be combined with the deprecated host, hosts, domain, domains or no-hostname fields")
+	}
+	cfRoutes := parseLegacyRoutes(cfApp)
+	routeSpec := parseRouteSpec(&cfRoutes, cfApp.RandomRoute, cfApp.NoRoute)
+	routeSpec.Legacy = !routeSpec.NoRoute
+	return routeSpec, nil
+}
+
+// DefaultDomainPlaceholder stands for the default shared domain of the foundation in the routes derived from the
+// deprecated `host` and `hosts` fields of an application without `domain` or `domains`. It uses the reserved
+// `.invalid` top level domain, so that the routes can't resolve until it is replaced.
+const DefaultDomainPlaceholder = "default-domain.invalid"
+
+func hasLegacyRouteAttributes(cfApp AppManifest) bool {
+	return cfApp.Host != "" || len(cfApp.Hosts) > 0 || cfApp.Domain != "" || len(cfApp.Domains) > 0 || cfApp.NoHostname
+}
+
+// parseLegacyRoutes expands the deprecated route attributes into the list of routes that the CF CLI
+// creates for them: the cartesian product of the hosts and the domains. The application name is used
+// as host when none is provided, and no host is used when `no-hostname` is set. Without domains the CF CLI
+// falls back to the default shared domain of the foundation, which is unknown at this stage, so the hosts
+// are kept on DefaultDomainPlaceholder for the user to replace.
+func parseLegacyRoutes(cfApp AppManifest) AppManifestRoutes {
+	hosts := appendUnique(nil, cfApp.Host)
+	for _, h := range cfApp.Hosts {
+		hosts = appendUnique(hosts, h)
+	}
+	if len(hosts) == 0 {
+		hosts = []string{cfApp.Name}
+	}
+	if cfApp.NoHostname {
+		hosts = []string{""}
+	}
+	domains := appendUnique(nil, cfApp.Domain)
+	for _, d := range cfApp.Domains {
+		domains = appendUnique(domains, d)
+	}
+	if len(domains) == 0 {
+		domains = []string{DefaultDomainPlaceholder}
+	}
+
+	routes := AppManifestRoutes{}
+	seen := []string{}
+	for _, h := range hosts {
+		for _, d := range domains {
+			r := d
+			if h != "" {
+				r = h + "." + d
+			}
+			if slices.Contains(seen, r) {
+				continue
+			}
+			seen = append(seen, r)
+			routes = append(routes, AppManifestRoute{Route: r})
+		}
+	}
+	return routes
+}
+
+func appendUnique(values []string, v string) []string {
+	if v == "" || slices.Contains(values, v) {
+		return values
+	}
+	return append(values, v)
+}
+
 func parseRouteSpec(cfRoutes *AppManifestRoutes, randomRoute, noRoute bool) RouteSpec {
 	if noRoute {
 		return RouteSpec{
@@ -361,6 +361,59 @@ var _ = Describe("Parse Routes", func() {
 
 })
 
+var _ = Describe("Parse legacy routes", func() {
+
+	When("parsing the deprecated route attributes", func() {
+		DescribeTable("validate the expansion of hosts and domains into routes", func(app AppManifest, expected AppManifestRoutes) {
+			result := parseLegacyRoutes(app)
+			Expect(result).To(Equal(expected))
+		},
+			Entry("when only the domain is set the application name is used as host",
+				AppManifest{Name: "foo", Domain: "example.com"},
+				AppManifestRoutes{{Route: "foo.example.com"}}),
+			Entry("when host and domain are set",
+				AppManifest{Name: "foo", Host: "bar", Domain: "example.com"},
+				AppManifestRoutes{{Route: "bar.example.com"}}),
+			Entry("when multiple hosts and domains are set",
+				AppManifest{Name: "foo", Host: "a", Hosts: []string{"b", "a"}, Domain: "example.com", Domains: []string{"example.org"}},
+				AppManifestRoutes{{Route: "a.example.com"}, {Route: "a.example.org"}, {Route: "b.example.com"}, {Route: "b.example.org"}}),
+			Entry("when no-hostname is set",
+				AppManifest{Name: "foo", Host: "bar", NoHostname: true, Domains: []string{"example.com", "example.org"}},
+				AppManifestRoutes{{Route: "example.com"}, {Route: "example.org"}}),
+			Entry("when no domain is set the hosts use the placeholder of the default domain",
+				AppManifest{Name: "foo", Host: "bar", Hosts: []string{"baz"}},
+				AppManifestRoutes{{Route: "bar.default-domain.invalid"}, {Route: "baz.default-domain.invalid"}}),
+			Entry("when no domain and no-hostname are set",
+				AppManifest{Name: "foo", NoHostname: true},
+				AppManifestRoutes{{Route: "default-domain.invalid"}}),
+		)
+
+		DescribeTable("validate the resulting route specification", func(app AppManifest, expected RouteSpec) {
+			result, err := parseAppRouteSpec(app)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(expected))
+		},
+			Entry("when only the routes field is set",
+				AppManifest{Routes: &AppManifestRoutes{{Route: "foo.bar"}}},
+				RouteSpec{Routes: Routes{{Route: "foo.bar"}}}),
+			Entry("when the deprecated attributes are set",
+				AppManifest{Name: "foo", Domain: "example.com", RandomRoute: true},
+				RouteSpec{RandomRoute: true, Legacy: true, Routes: Routes{{Route: "foo.example.com"}}}),
+			Entry("when only the hosts are set",
+				AppManifest{Name: "foo", Hosts: []string{"bar"}},
+				RouteSpec{Legacy: true, Routes: Routes{{Route: "bar.default-domain.invalid"}}}),
+			Entry("when the deprecated attributes are set and no-route is true",
+				AppManifest{Name: "foo", Domain: "example.com", NoRoute: true},
+				RouteSpec{NoRoute: true}),
+		)
+
+		It("fails when the routes field is combined with the deprecated attributes", func() {
+			_, err := parseAppRouteSpec(AppManifest{Domain: "example.com", Routes: &AppManifestRoutes{{Route: "foo.bar"}}})
+			Expect(err).To(MatchError(ContainSubstring("cannot be combined")))
+		})
+	})
+})
+
 var _ = Describe("parse Services", func() {
 	When("parsing the service information", func() {
 		DescribeTable("validate the correctness of the parsing logic", func(services AppManifestServices, expected Services) {
@@ -492,7 +545,7 @@ var _ = Describe("Parse Application", func() {
 			),
 			Entry("when environment values are set",
 				AppManifest{
-					Env: map[string]string{"foo": "bar"},
+					Env: map[string]interface{}{"foo": "bar"},
 				},
 				"",
 				"",
@@ -519,7 +572,7 @@ var _ = Describe("Parse Application", func() {
 							Options:  &AppRouteOptions{LoadBalancing: "least-connection"},
 						},
 					},
-					Env: map[string]string{"foo": "bar"},
+					Env: map[string]interface{}{"foo": "bar"},
 					Services: &AppManifestServices{
 						{
 							Name:        "foo",
@@ -619,6 +672,7 @@ var _ = Describe("Parse Application", func() {
 							Instances:    2,
 							LogRateLimit: "30k",
 							Memory:       "2G",
+							Sidecars:     []string{"foo_sidecar"},
 							Lifecycle:    "container",
 							HealthCheck: ProbeSpec{
 								Endpoint: "/health",
@@ -5,8 +5,12 @@ package cloud_foundry
 type Application struct {
 	// Metadata captures the name, labels and annotations in the application.
 	Metadata Metadata `yaml:",inline" validate:"required"`
-	// Env captures the `env` field values in the CF application manifest.
+	// Env captures the `env` field values in the CF application manifest. Values that are not strings are
+	// converted the same way Cloud Foundry does: numbers and booleans to their string representation and
+	// objects and arrays to JSON.
 	Env map[string]string `yaml:"env,omitempty"`
+	// EnvTypes captures the original type of the `env` field values that were not strings in the CF application manifest.
+	EnvTypes map[string]EnvValueType `yaml:"envTypes,omitempty"`
 	// Routes represent the routes that are made available by the application.
 	Routes RouteSpec `yaml:"routes,inline,omitempty"`
 	// Services captures the `services` field values in the CF application manifest.
@@ -30,6 +34,12 @@ type Application struct {
 	BuildPacks []string `yaml:"buildPacks,omitempty"`
 	// Docker captures the Docker specification in the CF application manifest.
 	Docker Docker `yaml:"docker,omitempty"`
+	// Path captures the `path` field in the CF application manifest, resolved relative to the location of the manifest.
+	// It points to the directory or archive that contains the source code of the application.
+	Path string `yaml:"path,omitempty"`
+	// Source captures the information found in the location referenced by Path. It is only populated when the
+	// source inspection is requested.
+	Source *SourceSpec `yaml:"source,omitempty"`
 	// Instances captures the number of instances to run concurrently for this application. Default is 1.
 	Instances int `yaml:"instances" validate:"required,min=1"`
 }
@@ -56,7 +66,7 @@ type SidecarSpec struct {
 	Command string `yaml:"command" validate:"required"`
 	// Memory represents the amount of memory to allocate to the sidecar.
 	// It's an optional field.
-	Memory string `yaml:"memory,omitempty"`
+	Memory Quantity `yaml:"memory,omitempty" validate:"omitempty,quantity"`
 }
 
 type ServiceSpec struct {
@@ -69,6 +79,9 @@ type ServiceSpec struct {
 	Parameters map[string]interface{} `yaml:"parameters,omitempty"`
 	// BindingName captures the name of the service to bind to.
 	BindingName string `yaml:"bindingName,omitempty"`
+	// Credentials captures the credentials of the service binding. It is only populated when the application is
+	// discovered from the Cloud Foundry API and the credentials are explicitly requested.
+	Credentials map[string]interface{} `yaml:"credentials,omitempty"`
 }
 
 type Metadata struct {
@@ -77,6 +90,9 @@ type Metadata struct {
 	// Space captures the `space` where the CF application is deployed at runtime. The field is empty if the
 	// application is discovered directly from the CF manifest. It is equivalent to a Namespace in Kubernetes.
 	Space string `yaml:"space,omitempty"`
+	// Organization captures the organization that contains the `space` where the CF application is deployed at runtime.
+	// The field is empty if the application is discovered directly from the CF manifest.
+	Organization string `yaml:"organization,omitempty"`
 	// Labels capture the labels as defined in the `annotations` field in the CF application manifest
 	Labels map[string]*string `yaml:"labels,omitempty"`
 	// Annotations capture the annotations as defined in the `labels` field in the CF application manifest
@@ -94,20 +110,26 @@ type ProcessSpec struct {
 	// Command represents the command used to run the process.
 	Command string `yaml:"command,omitempty"`
 	// DiskQuota represents the amount of persistent disk requested by the process.
-	DiskQuota string `yaml:"disk,omitempty"`
+	DiskQuota Quantity `yaml:"disk,omitempty" validate:"omitempty,quantity"`
 	// Memory represents the amount of memory requested by the process.
-	Memory string `yaml:"memory" validate:"required"`
+	Memory Quantity `yaml:"memory" validate:"required,quantity"`
 	// HealthCheck captures the health check information
 	HealthCheck ProbeSpec `yaml:"healthCheck"`
 	// ReadinessCheck captures the readiness check information.
 	ReadinessCheck ProbeSpec `yaml:"readinessCheck"`
 	// Instances represents the number of instances for this process to run.
 	Instances int `yaml:"instances" validate:"required,min=1"`
-	// LogRateLimit represents the maximum amount of logs to be captured per second. Defaults to `16K`
-	LogRateLimit string `yaml:"logRateLimit" validate:"required"`
+	// LogRateLimit represents the maximum amount of logs to be captured per second. Defaults to `16K`, and `-1`
+	// disables the limit.
+	LogRateLimit Quantity `yaml:"logRateLimit" validate:"required,quantity=unlimited"`
+	// Sidecars lists the names of the sidecars that run with the process.
+	Sidecars []string `yaml:"sidecars,omitempty"`
+	// EffectiveMemory is the memory left to the process by the sidecars that run with it, which Cloud Foundry counts
+	// against the memory of the process. It is only set when the sidecars have memory.
+	EffectiveMemory Quantity `yaml:"effectiveMemory,omitempty"`
 	// Lifecycle captures the value fo the lifecycle field in the CF application manifest.
-	// Valid values are `buildpack`, `cnb`, and `docker`. Defaults to `buildpack`
-	Lifecycle LifecycleType `yaml:"lifecycle,omitempty" validate:"required,oneof=buildpack cnb docker"`
+	// Valid values are `buildpack`, `cnb`, and `docker`. An empty value means `buildpack`
+	Lifecycle LifecycleType `yaml:"lifecycle,omitempty" validate:"omitempty,oneof=buildpack cnb docker"`
 }
 
 type LifecycleType string
@@ -156,21 +178,24 @@ type RouteSpec struct {
 	RandomRoute bool `yaml:"randomRoute,omitempty"`
 	//Routes captures the field routes in the CF Application manifest.
 	Routes Routes `yaml:"routes,omitempty"`
+	//Legacy is true when the routes have been derived from the deprecated `host`, `hosts`, `domain`, `domains`
+	//and `no-hostname` fields in the CF Application manifest instead of the `routes` field.
+	Legacy bool `yaml:"legacyRoutes,omitempty"`
 }
 
 type Route struct {
 	// Route captures the domain name, port and path of the route.
 	Route string `yaml:"route" validate:"required"`
-	// Protocol captures the protocol type: http, http2 or tcp. Note that the CF `protocol` field is only available
-	// for CF deployments that use HTTP/2 routing.
-	Protocol RouteProtocol `yaml:"protocol,omitempty" validate:"required,oneof=http http2 tcp"`
+	// Protocol captures the protocol type: http1, http2 or tcp. Note that the CF `protocol` field is only available
+	// for CF deployments that use HTTP/2 routing. An empty value means http1.
+	Protocol RouteProtocol `yaml:"protocol,omitempty" validate:"omitempty,oneof=http1 http2 tcp"`
 	// Options captures the options for the Route. Only load balancing is supported at the moment.
 	Options RouteOptions `yaml:"options,omitempty"`
 }
 
 type RouteOptions struct {
-	// LoadBalancing captures the settings for load balancing. Only `round-robin` or `least-connections` are supported
-	LoadBalancing LoadBalancingType `yaml:"loadBalancing,omitempty" validate:"oneof=round-robin least-connections"`
+	// LoadBalancing captures the settings for load balancing. Only `round-robin` or `least-connection` are supported
+	LoadBalancing LoadBalancingType `yaml:"loadBalancing,omitempty" validate:"omitempty,oneof=round-robin least-connection"`
 }
 
 type LoadBalancingType string
@@ -0,0 +1,93 @@
+package cloud_foundry
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type EnvValueType string
+
+const (
+	StringEnvValueType  EnvValueType = "string"
+	NumberEnvValueType  EnvValueType = "number"
+	BooleanEnvValueType EnvValueType = "boolean"
+	ObjectEnvValueType  EnvValueType = "object"
+	ArrayEnvValueType   EnvValueType = "array"
+	NullEnvValueType    EnvValueType = "null"
+)
+
+// parseEnv normalizes the values in the `env` field the same way Cloud Foundry does: strings are kept as they are,
+// numbers and booleans are converted to their string representation, and objects and arrays are encoded as JSON.
+// It also returns the original type of the values that were not strings.
+func parseEnv(cfEnv map[string]interface{}) (map[string]string, map[string]EnvValueType, error) {
+	if cfEnv == nil {
+		return nil, nil, nil
+	}
+	env := make(map[string]string, len(cfEnv))
+	var types map[string]EnvValueType
+	for k, v := range cfEnv {
+		value, t, err := parseEnvValue(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid value for environment variable %q: %w", k, err)
+		}
+		env[k] = value
+		if t != StringEnvValueType {
+			if types == nil {
+				types = map[string]EnvValueType{}
+			}
+			types[k] = t
+		}
+	}
+	return env, types, nil
+}
+
+func parseEnvValue(v interface{}) (string, EnvValueType, error) {
+	var t EnvValueType
+	switch v := v.(type) {
+	case nil:
+		return "", NullEnvValueType, nil
+	case string:
+		return v, StringEnvValueType, nil
+	case bool:
+		t = BooleanEnvValueType
+	case int, int64, uint64, float64:
+		t = NumberEnvValueType
+	case map[string]interface{}, map[interface{}]interface{}:
+		t = ObjectEnvValueType
+	case []interface{}:
+		t = ArrayEnvValueType
+	default:
+		return fmt.Sprint(v), StringEnvValueType, nil
+	}
+	b, err := json.Marshal(toJSONValue(v))
+	if err != nil {
+		return "", "", err
+	}
+	return string(b), t, nil
+}
+
+// toJSONValue converts the maps with non string keys produced by the YAML decoder into maps that
+// can be encoded as JSON.
+func toJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = toJSONValue(e)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = toJSONValue(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = toJSONValue(e)
+		}
+		return l
+	}
+	return v
+}
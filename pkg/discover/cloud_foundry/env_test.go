@@ -0,0 +1,52 @@
+package cloud_foundry
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+var _ = Describe("Parse env", func() {
+
+	When("parsing the environment values", func() {
+		DescribeTable("validate the normalization of the values", func(manifestEnv string, expectedEnv map[string]string, expectedTypes map[string]EnvValueType) {
+			cfEnv := map[string]interface{}{}
+			Expect(yaml.Unmarshal([]byte(manifestEnv), &cfEnv)).To(Succeed())
+			env, types, err := parseEnv(cfEnv)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(env).To(Equal(expectedEnv))
+			Expect(types).To(Equal(expectedTypes))
+		},
+			Entry("with string values", "FOO: bar\nEMPTY: ''", map[string]string{"FOO": "bar", "EMPTY": ""}, nil),
+			Entry("with quoted scalars", "DEBUG: 'true'\nWORKERS: '4'", map[string]string{"DEBUG": "true", "WORKERS": "4"}, nil),
+			Entry("with a boolean", "DEBUG: true", map[string]string{"DEBUG": "true"}, map[string]EnvValueType{"DEBUG": BooleanEnvValueType}),
+			Entry("with numbers", "WORKERS: 4\nRATIO: 0.75", map[string]string{"WORKERS": "4", "RATIO": "0.75"}, map[string]EnvValueType{"WORKERS": NumberEnvValueType, "RATIO": NumberEnvValueType}),
+			Entry("with a null value", "UNSET: ~", map[string]string{"UNSET": ""}, map[string]EnvValueType{"UNSET": NullEnvValueType}),
+			Entry("with an object", "CONFIG:\n  b: [1, 2]\n  a: {enabled: true}",
+				map[string]string{"CONFIG": `{"a":{"enabled":true},"b":[1,2]}`},
+				map[string]EnvValueType{"CONFIG": ObjectEnvValueType}),
+			Entry("with an object with non string keys", "CONFIG: {1: one}",
+				map[string]string{"CONFIG": `{"1":"one"}`},
+				map[string]EnvValueType{"CONFIG": ObjectEnvValueType}),
+			Entry("with an array", "HOSTS: [a, b]", map[string]string{"HOSTS": `["a","b"]`}, map[string]EnvValueType{"HOSTS": ArrayEnvValueType}),
+		)
+
+		It("returns nil when there are no values", func() {
+			env, types, err := parseEnv(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(env).To(BeNil())
+			Expect(types).To(BeNil())
+		})
+	})
+
+	When("parsing a manifest with typed environment values", func() {
+		It("discovers the application", func() {
+			m, err := ParseManifest([]byte("applications:\n- name: foo\n  env:\n    DEBUG: true\n    WORKERS: 4\n    NAME: foo\n"), nil)
+			Expect(err).NotTo(HaveOccurred())
+			app, err := Discover(*m.Applications[0], m.Version, m.Space)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(app.Env).To(Equal(map[string]string{"DEBUG": "true", "WORKERS": "4", "NAME": "foo"}))
+			Expect(app.EnvTypes).To(Equal(map[string]EnvValueType{"DEBUG": BooleanEnvValueType, "WORKERS": NumberEnvValueType}))
+		})
+	})
+})
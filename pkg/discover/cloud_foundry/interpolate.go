@@ -0,0 +1,172 @@
+package cloud_foundry
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Variables contains the values used to resolve the `((variable))` placeholders in a manifest, in the
+// same way as `cf push --vars-file` and `cf push --var` do.
+type Variables map[string]interface{}
+
+// variablePattern matches `((name))` placeholders. Names can use dots to reference nested values in a
+// vars file, and the `!` prefix supported by the CF CLI is ignored.
+var variablePattern = regexp.MustCompile(`\(\(!?([-\w./:]+)\)\)`)
+
+// UnresolvedVariable describes a placeholder in the manifest that has no value.
+type UnresolvedVariable struct {
+	Name   string
+	Line   int
+	Column int
+}
+
+// UnresolvedVariablesError is returned when one or more placeholders in the manifest could not be resolved.
+type UnresolvedVariablesError []UnresolvedVariable
+
+func (e UnresolvedVariablesError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, v := range e {
+		msgs = append(msgs, fmt.Sprintf("line %d, column %d: unresolved variable %q", v.Line, v.Column, v.Name))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// LoadVariables builds the variables from the given vars files and `key=value` pairs. Later files override
+// earlier ones and the inline pairs override the files. Inline values are decoded as YAML so that
+// `instances=3` yields a number, as it happens with `cf push --var`.
+func LoadVariables(varsFiles []string, pairs []string) (Variables, error) {
+	vars := Variables{}
+	for _, path := range varsFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading vars file %s: %w", path, err)
+		}
+		fileVars := map[string]interface{}{}
+		if err := yaml.Unmarshal(data, &fileVars); err != nil {
+			return nil, fmt.Errorf("error unmarshalling vars file %s: %w", path, err)
+		}
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+	for _, pair := range pairs {
+		k, raw, ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid variable %q: expected format key=value", pair)
+		}
+		var v interface{}
+		if err := yaml.Unmarshal([]byte(raw), &v); err != nil || v == nil || !isScalar(v) {
+			v = raw
+		}
+		vars[k] = v
+	}
+	return vars, nil
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+// interpolate replaces the placeholders in the values of the YAML document. A value that consists of a single
+// placeholder is replaced by the variable keeping its type, while placeholders embedded in a string are
+// replaced by the string representation of the variable. All unresolved placeholders are reported together.
+func interpolate(doc *yaml.Node, vars Variables) error {
+	unresolved := UnresolvedVariablesError{}
+	if err := interpolateNode(doc, vars, &unresolved); err != nil {
+		return err
+	}
+	if len(unresolved) > 0 {
+		return unresolved
+	}
+	return nil
+}
+
+func interpolateNode(n *yaml.Node, vars Variables, unresolved *UnresolvedVariablesError) error {
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			if err := interpolateNode(c, vars, unresolved); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		// Only the values are interpolated, keys are kept as they are.
+		for i := 1; i < len(n.Content); i += 2 {
+			if err := interpolateNode(n.Content[i], vars, unresolved); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return interpolateScalar(n, vars, unresolved)
+	}
+	return nil
+}
+
+func interpolateScalar(n *yaml.Node, vars Variables, unresolved *UnresolvedVariablesError) error {
+	if n.Tag != "!!str" {
+		return nil
+	}
+	matches := variablePattern.FindAllStringSubmatchIndex(n.Value, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(n.Value) {
+		name := n.Value[matches[0][2]:matches[0][3]]
+		v, ok := vars.lookup(name)
+		if !ok {
+			*unresolved = append(*unresolved, UnresolvedVariable{Name: name, Line: n.Line, Column: n.Column})
+			return nil
+		}
+		line, column := n.Line, n.Column
+		if err := n.Encode(v); err != nil {
+			return fmt.Errorf("line %d, column %d: error interpolating variable %q: %w", line, column, name, err)
+		}
+		return nil
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(n.Value[last:m[0]])
+		last = m[1]
+		name := n.Value[m[2]:m[3]]
+		v, ok := vars.lookup(name)
+		if !ok {
+			*unresolved = append(*unresolved, UnresolvedVariable{Name: name, Line: n.Line, Column: n.Column})
+			continue
+		}
+		if !isScalar(v) {
+			return fmt.Errorf("line %d, column %d: variable %q is not a scalar and can't be interpolated into a string", n.Line, n.Column, name)
+		}
+		fmt.Fprint(&b, v)
+	}
+	b.WriteString(n.Value[last:])
+	n.Value = b.String()
+	return nil
+}
+
+// lookup returns the value of the variable. Dots in the name traverse the nested maps of the variables.
+func (vars Variables) lookup(name string) (interface{}, bool) {
+	if v, ok := vars[name]; ok {
+		return v, true
+	}
+	parts := strings.Split(name, ".")
+	var current interface{} = map[string]interface{}(vars)
+	for _, p := range parts {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[p]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,116 @@
+package cloud_foundry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseManifest decodes the content of a CF application manifest after resolving its `((variable))`
+// placeholders with vars. Placeholders without a value are reported as an UnresolvedVariablesError.
+func ParseManifest(data []byte, vars Variables) (*Manifest, error) {
+	doc, err := parseManifestDocument(data, vars)
+	if err != nil {
+		return nil, err
+	}
+	m := Manifest{}
+	if err := doc.Decode(&m); err != nil {
+		return nil, fmt.Errorf("error unmarshalling manifest: %w", err)
+	}
+	return &m, nil
+}
+
+func parseManifestDocument(data []byte, vars Variables) (*yaml.Node, error) {
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error unmarshalling manifest: %w", err)
+	}
+	if err := interpolate(&doc, vars); err != nil {
+		return nil, fmt.Errorf("error interpolating manifest: %w", err)
+	}
+	return &doc, nil
+}
+
+// ReadManifest reads and decodes the CF application manifest located at path, resolving its
+// placeholders with vars. The relative `path` fields of the applications are resolved against
+// the directory of the manifest, as the CF CLI does.
+func ReadManifest(path string, vars Variables) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest %s: %w", path, err)
+	}
+	m, err := ParseManifest(data, vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	resolveAppPaths(m, filepath.Dir(path))
+	return m, nil
+}
+
+func resolveAppPaths(m *Manifest, dir string) {
+	for _, app := range m.Applications {
+		if app != nil && app.Path != "" && !filepath.IsAbs(app.Path) {
+			app.Path = filepath.Join(dir, app.Path)
+		}
+	}
+}
+
+// ReadLayeredManifest reads the manifests located at paths and merges them in order, so that each manifest
+// acts as an overlay of the previous ones. See MergeManifests for the merge rules. The placeholders of every
+// manifest are resolved with vars and the relative `path` fields are resolved against the directory of the
+// manifest that defines them.
+func ReadLayeredManifest(paths []string, vars Variables) (*Manifest, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no manifest provided")
+	}
+	if len(paths) == 1 {
+		return ReadManifest(paths[0], vars)
+	}
+	var merged map[string]interface{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading manifest %s: %w", path, err)
+		}
+		doc, err := parseManifestDocument(data, vars)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		// Decode each layer on its own so that errors are reported with the location in the file that causes them.
+		if err := doc.Decode(&Manifest{}); err != nil {
+			return nil, fmt.Errorf("%s: error unmarshalling manifest: %w", path, err)
+		}
+		layer := map[string]interface{}{}
+		if err := doc.Decode(&layer); err != nil {
+			return nil, fmt.Errorf("%s: error unmarshalling manifest: %w", path, err)
+		}
+		resolveRawAppPaths(layer, filepath.Dir(path))
+		if merged, err = MergeManifests(merged, layer); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	b, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling merged manifest: %w", err)
+	}
+	m := Manifest{}
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("error unmarshalling merged manifest: %w", err)
+	}
+	return &m, nil
+}
+
+func resolveRawAppPaths(manifest map[string]interface{}, dir string) {
+	apps, _ := manifest["applications"].([]interface{})
+	for _, a := range apps {
+		app, ok := a.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if p, ok := app["path"].(string); ok && p != "" && !filepath.IsAbs(p) {
+			app["path"] = filepath.Join(dir, p)
+		}
+	}
+}
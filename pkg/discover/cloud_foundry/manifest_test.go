@@ -0,0 +1,89 @@
+package cloud_foundry
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testdataDir = "../../../resources/cloud_foundry/testdata/"
+
+var _ = Describe("Read manifest", func() {
+	When("reading a manifest file", func() {
+		It("decodes all the applications in the manifest", func() {
+			m, err := ReadManifest(testdataDir+"cf_doc_sample.yaml", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Version).To(Equal("1"))
+			Expect(m.Space).To(Equal("default"))
+			Expect(m.Applications).To(HaveLen(2))
+			Expect(m.Applications[0].Name).To(Equal("app1"))
+			Expect(m.Applications[1].Name).To(Equal("app2"))
+		})
+		It("fails when the file does not exist", func() {
+			_, err := ReadManifest(testdataDir+"does_not_exist.yaml", nil)
+			Expect(err).To(MatchError(ContainSubstring("error reading manifest")))
+		})
+		It("fails when the content is not a valid manifest", func() {
+			_, err := ParseManifest([]byte("applications: foo"), nil)
+			Expect(err).To(MatchError(ContainSubstring("error unmarshalling manifest")))
+		})
+	})
+})
+
+var _ = Describe("Interpolate manifest", func() {
+	When("loading variables", func() {
+		It("merges the vars files and the inline variables", func() {
+			vars, err := LoadVariables([]string{testdataDir + "cf_vars_sample_vars.yaml"}, []string{"instances=4", "domain=apps.internal", "empty="})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vars).To(HaveKeyWithValue("app-name", "my-app"))
+			Expect(vars).To(HaveKeyWithValue("instances", 4))
+			Expect(vars).To(HaveKeyWithValue("domain", "apps.internal"))
+			Expect(vars).To(HaveKeyWithValue("empty", ""))
+			Expect(vars).To(HaveKeyWithValue("database", map[string]interface{}{"host": "db.example.com", "port": 5432}))
+		})
+		It("fails when an inline variable has no key", func() {
+			_, err := LoadVariables(nil, []string{"=foo"})
+			Expect(err).To(MatchError(ContainSubstring("expected format key=value")))
+		})
+		It("fails when a vars file does not exist", func() {
+			_, err := LoadVariables([]string{testdataDir + "does_not_exist.yaml"}, nil)
+			Expect(err).To(MatchError(ContainSubstring("error reading vars file")))
+		})
+	})
+
+	When("reading a manifest with placeholders", func() {
+		It("resolves the variables keeping their type", func() {
+			vars, err := LoadVariables([]string{testdataDir + "cf_vars_sample_vars.yaml"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			m, err := ReadManifest(testdataDir+"cf_vars_sample.yaml", vars)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Applications).To(HaveLen(1))
+			app := m.Applications[0]
+			Expect(app.Name).To(Equal("my-app"))
+			Expect(app.Instances).To(Equal(ptrTo(uint(2))))
+			Expect(app.Memory).To(Equal("512M"))
+			Expect(app.Env).To(Equal(map[string]interface{}{
+				"DATABASE_HOST": "db.example.com",
+				"DATABASE_URL":  "postgres://db.example.com:5432/app",
+			}))
+			Expect(*app.Routes).To(Equal(AppManifestRoutes{{Route: "my-app.example.com"}}))
+		})
+		It("reports every unresolved variable with its location", func() {
+			_, err := ReadManifest(testdataDir+"cf_vars_sample.yaml", Variables{"app-name": "foo", "memory": "1G"})
+			var unresolved UnresolvedVariablesError
+			Expect(errors.As(err, &unresolved)).To(BeTrue())
+			Expect(unresolved).To(Equal(UnresolvedVariablesError{
+				{Name: "instances", Line: 4, Column: 16},
+				{Name: "database.host", Line: 7, Column: 22},
+				{Name: "database.host", Line: 8, Column: 21},
+				{Name: "database.port", Line: 8, Column: 21},
+				{Name: "domain", Line: 10, Column: 16},
+			}))
+		})
+		It("fails when a structured variable is embedded in a string", func() {
+			_, err := ParseManifest([]byte("applications:\n- name: app-((foo))\n"), Variables{"foo": map[string]interface{}{"bar": 1}})
+			Expect(err).To(MatchError(ContainSubstring(`variable "foo" is not a scalar`)))
+		})
+	})
+})
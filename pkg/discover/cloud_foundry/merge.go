@@ -0,0 +1,174 @@
+package cloud_foundry
+
+import (
+	"fmt"
+	"slices"
+)
+
+// legacyRouteFields are the deprecated route fields that are replaced together with the `routes` field.
+var legacyRouteFields = []string{"host", "hosts", "domain", "domains", "no-hostname"}
+
+// MergeManifests deep merges the overlay manifest into the base manifest and returns the result. Both manifests are
+// the generic representation of a CF application manifest, as decoded from YAML. Neither of them is modified.
+//
+// Applications are matched by name, and applications that only exist in the overlay are appended. For each
+// application:
+//   - scalar fields in the overlay replace the ones in the base.
+//   - maps, like `env` and `metadata`, are merged recursively.
+//   - `routes` in the overlay replace all the routes in the base, including the ones derived from the deprecated
+//     `host`, `hosts`, `domain`, `domains` and `no-hostname` fields. The same applies when the overlay uses the
+//     deprecated fields.
+//   - `services` are merged by service name, `processes` by type and `sidecars` by name. Entries in the overlay
+//     are merged recursively into the matching entry in the base, and appended when there is no match.
+//   - any other list, like `buildpacks`, is replaced.
+func MergeManifests(base, overlay map[string]interface{}) (map[string]interface{}, error) {
+	if base == nil {
+		return copyMap(overlay), nil
+	}
+	merged := copyMap(base)
+	for k, v := range overlay {
+		if k != "applications" {
+			merged[k] = mergeValues(merged[k], v)
+			continue
+		}
+		apps, err := mergeApplications(merged[k], v)
+		if err != nil {
+			return nil, err
+		}
+		merged[k] = apps
+	}
+	return merged, nil
+}
+
+func mergeApplications(base, overlay interface{}) ([]interface{}, error) {
+	baseApps, _ := base.([]interface{})
+	overlayApps, ok := overlay.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("applications must be a list")
+	}
+	merged := slices.Clone(baseApps)
+	for _, o := range overlayApps {
+		overlayApp, ok := o.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("applications must be a list of objects")
+		}
+		name, _ := overlayApp["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("applications in an overlay manifest must have a name")
+		}
+		i := indexByKey(merged, "name", name)
+		if i < 0 {
+			merged = append(merged, copyMap(overlayApp))
+			continue
+		}
+		baseApp, _ := merged[i].(map[string]interface{})
+		merged[i] = mergeApplication(baseApp, overlayApp)
+	}
+	return merged, nil
+}
+
+func mergeApplication(base, overlay map[string]interface{}) map[string]interface{} {
+	merged := copyMap(base)
+	if _, ok := overlay["routes"]; ok || hasAnyKey(overlay, legacyRouteFields) {
+		delete(merged, "routes")
+		for _, f := range legacyRouteFields {
+			delete(merged, f)
+		}
+	}
+	for k, v := range overlay {
+		switch k {
+		case "services":
+			merged[k] = mergeListByKey(normalizeServices(merged[k]), normalizeServices(v), "name")
+		case "processes":
+			merged[k] = mergeListByKey(merged[k], v, "type")
+		case "sidecars":
+			merged[k] = mergeListByKey(merged[k], v, "name")
+		default:
+			merged[k] = mergeValues(merged[k], v)
+		}
+	}
+	return merged
+}
+
+// mergeValues merges maps recursively. Any other overlay value replaces the base value.
+func mergeValues(base, overlay interface{}) interface{} {
+	baseMap, ok := base.(map[string]interface{})
+	overlayMap, isMap := overlay.(map[string]interface{})
+	if !ok || !isMap {
+		return overlay
+	}
+	merged := copyMap(baseMap)
+	for k, v := range overlayMap {
+		merged[k] = mergeValues(merged[k], v)
+	}
+	return merged
+}
+
+// mergeListByKey merges the entries of both lists that have the same value in key, and appends the overlay
+// entries that don't match any entry in the base list.
+func mergeListByKey(base, overlay interface{}, key string) interface{} {
+	overlayList, ok := overlay.([]interface{})
+	if !ok {
+		return overlay
+	}
+	baseList, _ := base.([]interface{})
+	merged := slices.Clone(baseList)
+	for _, o := range overlayList {
+		entry, ok := o.(map[string]interface{})
+		if !ok {
+			merged = append(merged, o)
+			continue
+		}
+		i := indexByKey(merged, key, entry[key])
+		if i < 0 {
+			merged = append(merged, copyMap(entry))
+			continue
+		}
+		merged[i] = mergeValues(merged[i], entry)
+	}
+	return merged
+}
+
+// normalizeServices converts the services that are listed only by name into objects, so that they can be
+// merged with the services defined as objects.
+func normalizeServices(services interface{}) interface{} {
+	list, ok := services.([]interface{})
+	if !ok {
+		return services
+	}
+	normalized := make([]interface{}, 0, len(list))
+	for _, s := range list {
+		if name, ok := s.(string); ok {
+			normalized = append(normalized, map[string]interface{}{"name": name})
+			continue
+		}
+		normalized = append(normalized, s)
+	}
+	return normalized
+}
+
+func indexByKey(list []interface{}, key string, value interface{}) int {
+	for i, e := range list {
+		if m, ok := e.(map[string]interface{}); ok && m[key] == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasAnyKey(m map[string]interface{}, keys []string) bool {
+	for _, k := range keys {
+		if _, ok := m[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,134 @@
+package cloud_foundry
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Merge manifests", func() {
+
+	When("merging generic manifests", func() {
+		DescribeTable("validate the merge rules", func(base, overlay, expected map[string]interface{}) {
+			result, err := MergeManifests(base, overlay)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(expected))
+		},
+			Entry("when there is no base",
+				nil,
+				map[string]interface{}{"version": 1},
+				map[string]interface{}{"version": 1}),
+			Entry("when top level scalars are overridden",
+				map[string]interface{}{"version": 1, "space": "dev"},
+				map[string]interface{}{"space": "prod"},
+				map[string]interface{}{"version": 1, "space": "prod"}),
+			Entry("when env and metadata are merged recursively",
+				map[string]interface{}{"applications": []interface{}{
+					map[string]interface{}{"name": "foo", "env": map[string]interface{}{"A": "1", "B": "2"}, "metadata": map[string]interface{}{"labels": map[string]interface{}{"team": "a"}}},
+				}},
+				map[string]interface{}{"applications": []interface{}{
+					map[string]interface{}{"name": "foo", "env": map[string]interface{}{"B": "3"}, "metadata": map[string]interface{}{"labels": map[string]interface{}{"tier": "1"}}},
+				}},
+				map[string]interface{}{"applications": []interface{}{
+					map[string]interface{}{"name": "foo", "env": map[string]interface{}{"A": "1", "B": "3"}, "metadata": map[string]interface{}{"labels": map[string]interface{}{"team": "a", "tier": "1"}}},
+				}}),
+			Entry("when routes replace the routes and the deprecated route fields",
+				map[string]interface{}{"applications": []interface{}{
+					map[string]interface{}{"name": "foo", "host": "foo", "domain": "dev.example.com", "routes": []interface{}{map[string]interface{}{"route": "a.dev"}}},
+				}},
+				map[string]interface{}{"applications": []interface{}{
+					map[string]interface{}{"name": "foo", "routes": []interface{}{map[string]interface{}{"route": "a.prod"}}},
+				}},
+				map[string]interface{}{"applications": []interface{}{
+					map[string]interface{}{"name": "foo", "routes": []interface{}{map[string]interface{}{"route": "a.prod"}}},
+				}}),
+			Entry("when other lists are replaced",
+				map[string]interface{}{"applications": []interface{}{map[string]interface{}{"name": "foo", "buildpacks": []interface{}{"a", "b"}}}},
+				map[string]interface{}{"applications": []interface{}{map[string]interface{}{"name": "foo", "buildpacks": []interface{}{"c"}}}},
+				map[string]interface{}{"applications": []interface{}{map[string]interface{}{"name": "foo", "buildpacks": []interface{}{"c"}}}}),
+			Entry("when services are merged by name",
+				map[string]interface{}{"applications": []interface{}{
+					map[string]interface{}{"name": "foo", "services": []interface{}{"db", map[string]interface{}{"name": "cache", "binding_name": "c"}}},
+				}},
+				map[string]interface{}{"applications": []interface{}{
+					map[string]interface{}{"name": "foo", "services": []interface{}{map[string]interface{}{"name": "cache", "parameters": map[string]interface{}{"size": "l"}}, "queue"}},
+				}},
+				map[string]interface{}{"applications": []interface{}{
+					map[string]interface{}{"name": "foo", "services": []interface{}{
+						map[string]interface{}{"name": "db"},
+						map[string]interface{}{"name": "cache", "binding_name": "c", "parameters": map[string]interface{}{"size": "l"}},
+						map[string]interface{}{"name": "queue"},
+					}},
+				}}),
+			Entry("when processes are merged by type and sidecars by name",
+				map[string]interface{}{"applications": []interface{}{
+					map[string]interface{}{"name": "foo",
+						"processes": []interface{}{map[string]interface{}{"type": "web", "memory": "1G"}},
+						"sidecars":  []interface{}{map[string]interface{}{"name": "proxy", "command": "run"}}},
+				}},
+				map[string]interface{}{"applications": []interface{}{
+					map[string]interface{}{"name": "foo",
+						"processes": []interface{}{map[string]interface{}{"type": "web", "instances": 3}, map[string]interface{}{"type": "worker"}},
+						"sidecars":  []interface{}{map[string]interface{}{"name": "proxy", "memory": "64M"}}},
+				}},
+				map[string]interface{}{"applications": []interface{}{
+					map[string]interface{}{"name": "foo",
+						"processes": []interface{}{map[string]interface{}{"type": "web", "memory": "1G", "instances": 3}, map[string]interface{}{"type": "worker"}},
+						"sidecars":  []interface{}{map[string]interface{}{"name": "proxy", "command": "run", "memory": "64M"}}},
+				}}),
+			Entry("when the overlay adds an application",
+				map[string]interface{}{"applications": []interface{}{map[string]interface{}{"name": "foo"}}},
+				map[string]interface{}{"applications": []interface{}{map[string]interface{}{"name": "bar"}}},
+				map[string]interface{}{"applications": []interface{}{map[string]interface{}{"name": "foo"}, map[string]interface{}{"name": "bar"}}}),
+		)
+
+		It("fails when an application in the overlay has no name", func() {
+			_, err := MergeManifests(map[string]interface{}{}, map[string]interface{}{"applications": []interface{}{map[string]interface{}{"instances": 1}}})
+			Expect(err).To(MatchError(ContainSubstring("must have a name")))
+		})
+
+		It("does not modify the base manifest", func() {
+			base := map[string]interface{}{"applications": []interface{}{map[string]interface{}{"name": "foo", "env": map[string]interface{}{"A": "1"}}}}
+			_, err := MergeManifests(base, map[string]interface{}{"applications": []interface{}{map[string]interface{}{"name": "foo", "env": map[string]interface{}{"A": "2"}}}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(base).To(Equal(map[string]interface{}{"applications": []interface{}{map[string]interface{}{"name": "foo", "env": map[string]interface{}{"A": "1"}}}}))
+		})
+	})
+
+	When("reading layered manifests", func() {
+		It("discovers the effective configuration", func() {
+			m, err := ReadLayeredManifest([]string{testdataDir + "cf_layered_base.yaml", testdataDir + "cf_layered_prod.yaml"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Space).To(Equal("production"))
+			Expect(m.Applications).To(HaveLen(3))
+
+			app, err := Discover(*m.Applications[0], m.Version, m.Space)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(app.Metadata.Name).To(Equal("my-app"))
+			Expect(app.Path).To(Equal(filepath.Join(testdataDir, "my-app")))
+			Expect(app.Instances).To(Equal(4))
+			Expect(app.BuildPacks).To(Equal([]string{"java_buildpack"}))
+			Expect(app.Env).To(Equal(map[string]string{"LOG_LEVEL": "warn", "FEATURE_X": "enabled", "DATABASE_POOL": "20"}))
+			Expect(app.Routes.Routes).To(Equal(Routes{{Route: "my-app.example.com"}, {Route: "www.example.com"}}))
+			Expect(app.Services).To(Equal(Services{
+				{Name: "my-database"},
+				{Name: "my-cache", Parameters: map[string]interface{}{"size": "large"}},
+				{Name: "my-queue"},
+			}))
+			Expect(app.Processes).To(HaveLen(2))
+			Expect(app.Processes[0].Instances).To(Equal(4))
+			Expect(app.Processes[0].Memory).To(Equal(Quantity("2G")))
+			Expect(app.Processes[1].Command).To(Equal("./worker"))
+
+			Expect(m.Applications[1].Name).To(Equal("my-admin"))
+			Expect(m.Applications[2].Name).To(Equal("my-reporter"))
+			Expect(m.Applications[2].Path).To(Equal(filepath.Join(testdataDir, "reporter")))
+		})
+
+		It("reports the file that contains an invalid layer", func() {
+			_, err := ReadLayeredManifest([]string{testdataDir + "cf_layered_base.yaml", testdataDir + "cf_route_options_sample.yaml"}, nil)
+			Expect(err).To(MatchError(ContainSubstring("cf_route_options_sample.yaml")))
+		})
+	})
+})
@@ -0,0 +1,109 @@
+package cloud_foundry
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Quantity is an amount of bytes in the format of the Cloud Foundry manifests, like the `memory`, `disk_quota` and
+// `log-rate-limit-per-second` fields: a number followed by a unit, such as `512M`, `1GB` or `16k`. The units are
+// powers of 1024 and their spelling is case insensitive. A log rate limit of `-1` means unlimited.
+//
+// The quantity keeps the spelling it was written with, so it is marshaled back unchanged.
+type Quantity string
+
+// Units of the quantities.
+const (
+	Kilobyte int64 = 1 << (10 * (iota + 1))
+	Megabyte
+	Gigabyte
+	Terabyte
+)
+
+// unlimitedQuantity is the value of the log rate limit that disables it.
+const unlimitedQuantity = "-1"
+
+var quantityPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-zA-Z]*)$`)
+
+var quantityUnits = map[string]int64{
+	"B":  1,
+	"K":  Kilobyte,
+	"KB": Kilobyte,
+	"M":  Megabyte,
+	"MB": Megabyte,
+	"G":  Gigabyte,
+	"GB": Gigabyte,
+	"T":  Terabyte,
+	"TB": Terabyte,
+}
+
+// ParseQuantity parses s as a Cloud Foundry amount of bytes. It returns an error when s has no unit or an unknown one.
+func ParseQuantity(s string) (Quantity, error) {
+	q := Quantity(strings.TrimSpace(s))
+	if _, err := q.Bytes(); err != nil {
+		return "", err
+	}
+	return q, nil
+}
+
+// QuantityFromBytes returns the quantity with the given amount of bytes, in the largest unit that represents it exactly.
+func QuantityFromBytes(bytes int64) Quantity {
+	if bytes < 0 {
+		return unlimitedQuantity
+	}
+	for _, unit := range []string{"T", "G", "M", "K"} {
+		if size := quantityUnits[unit]; bytes != 0 && bytes%size == 0 {
+			return Quantity(strconv.FormatInt(bytes/size, 10) + unit)
+		}
+	}
+	return Quantity(strconv.FormatInt(bytes, 10) + "B")
+}
+
+// Bytes returns the amount of bytes of the quantity, which is -1 for an unlimited quantity.
+func (q Quantity) Bytes() (int64, error) {
+	s := strings.TrimSpace(string(q))
+	if s == unlimitedQuantity {
+		return -1, nil
+	}
+	m := quantityPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid quantity %q: must be a number followed by a unit such as M or G", string(q))
+	}
+	unit, ok := quantityUnits[strings.ToUpper(m[2])]
+	if !ok {
+		if m[2] == "" {
+			return 0, fmt.Errorf("invalid quantity %q: missing unit, must be one of B, K, KB, M, MB, G, GB, T or TB", string(q))
+		}
+		return 0, fmt.Errorf("invalid quantity %q: unknown unit %q, must be one of B, K, KB, M, MB, G, GB, T or TB", string(q), m[2])
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil || n*float64(unit) > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid quantity %q: out of range", string(q))
+	}
+	return int64(math.Round(n * float64(unit))), nil
+}
+
+// KubernetesQuantity returns the quantity as a Kubernetes quantity with a binary suffix, like `512Mi` or `1Gi`, in
+// the largest unit that represents it exactly.
+func (q Quantity) KubernetesQuantity() (string, error) {
+	bytes, err := q.Bytes()
+	if err != nil {
+		return "", err
+	}
+	if bytes < 0 {
+		return "", fmt.Errorf("invalid quantity %q: unlimited quantities can't be converted", string(q))
+	}
+	s := string(QuantityFromBytes(bytes))
+	if strings.HasSuffix(s, "B") {
+		return strings.TrimSuffix(s, "B"), nil
+	}
+	return s + "i", nil
+}
+
+// String returns the quantity as it was written.
+func (q Quantity) String() string {
+	return string(q)
+}
@@ -0,0 +1,66 @@
+package cloud_foundry
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+var _ = Describe("Quantity", func() {
+	DescribeTable("parses the Cloud Foundry units", func(s string, bytes int64, kubernetes string) {
+		q, err := ParseQuantity(s)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(q.Bytes()).To(Equal(bytes))
+		Expect(q.KubernetesQuantity()).To(Equal(kubernetes))
+	},
+		Entry("with bytes", "100B", int64(100), "100"),
+		Entry("with kilobytes", "16K", 16*Kilobyte, "16Ki"),
+		Entry("with lowercase kilobytes", "16kb", 16*Kilobyte, "16Ki"),
+		Entry("with megabytes", "512M", 512*Megabyte, "512Mi"),
+		Entry("with MB", "512MB", 512*Megabyte, "512Mi"),
+		Entry("with lowercase megabytes", "256mb", 256*Megabyte, "256Mi"),
+		Entry("with gigabytes", "1G", Gigabyte, "1Gi"),
+		Entry("with GB", "2GB", 2*Gigabyte, "2Gi"),
+		Entry("with lowercase gigabytes", "4g", 4*Gigabyte, "4Gi"),
+		Entry("with terabytes", "1T", Terabyte, "1Ti"),
+		Entry("with TB", "1tb", Terabyte, "1Ti"),
+		Entry("with megabytes that are a whole number of gigabytes", "2048M", 2*Gigabyte, "2Gi"),
+		Entry("with a decimal amount", "1.5G", 1536*Megabyte, "1536Mi"),
+		Entry("with a space before the unit", " 64 M ", 64*Megabyte, "64Mi"),
+	)
+
+	DescribeTable("rejects invalid quantities", func(s, message string) {
+		_, err := ParseQuantity(s)
+		Expect(err).To(MatchError(message))
+	},
+		Entry("with an empty value", "", `invalid quantity "": must be a number followed by a unit such as M or G`),
+		Entry("with no unit", "1024", `invalid quantity "1024": missing unit, must be one of B, K, KB, M, MB, G, GB, T or TB`),
+		Entry("with an unknown unit", "1Gi", `invalid quantity "1Gi": unknown unit "Gi", must be one of B, K, KB, M, MB, G, GB, T or TB`),
+		Entry("with garbage", "lots", `invalid quantity "lots": must be a number followed by a unit such as M or G`),
+		Entry("with a negative amount", "-2G", `invalid quantity "-2G": must be a number followed by a unit such as M or G`),
+	)
+
+	It("supports unlimited log rates", func() {
+		q, err := ParseQuantity("-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(q.Bytes()).To(Equal(int64(-1)))
+		_, err = q.KubernetesQuantity()
+		Expect(err).To(MatchError(`invalid quantity "-1": unlimited quantities can't be converted`))
+	})
+
+	It("builds quantities from bytes in the largest exact unit", func() {
+		Expect(QuantityFromBytes(3 * Gigabyte)).To(Equal(Quantity("3G")))
+		Expect(QuantityFromBytes(1536 * Megabyte)).To(Equal(Quantity("1536M")))
+		Expect(QuantityFromBytes(0)).To(Equal(Quantity("0B")))
+		Expect(QuantityFromBytes(-1)).To(Equal(Quantity("-1")))
+	})
+
+	It("marshals the quantities as they were written", func() {
+		b, err := yaml.Marshal(ProcessSpec{Type: Web, Memory: "512mb", DiskQuota: "1G", LogRateLimit: "16K", Instances: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring("disk: 1G\nmemory: 512mb\n"))
+		p := ProcessSpec{}
+		Expect(yaml.Unmarshal(b, &p)).To(Succeed())
+		Expect(p.Memory).To(Equal(Quantity("512mb")))
+	})
+})
@@ -0,0 +1,99 @@
+package cloud_foundry
+
+import (
+	"fmt"
+	"slices"
+)
+
+// SidecarsOf returns the sidecars that run with the processes of the given type.
+func (a Application) SidecarsOf(processType ProcessType) Sidecars {
+	sidecars := Sidecars{}
+	for _, s := range a.Sidecars {
+		if slices.Contains(s.ProcessTypes, processType) {
+			sidecars = append(sidecars, s)
+		}
+	}
+	return sidecars
+}
+
+// EffectiveMemory returns the memory left to the process by its sidecars, since Cloud Foundry counts the memory of
+// the sidecars against the memory of the process they run with. It fails when the sidecars use all the memory of
+// the process.
+func (a Application) EffectiveMemory(p ProcessSpec) (Quantity, error) {
+	var reserved int64
+	for _, s := range a.SidecarsOf(p.Type) {
+		if s.Memory == "" {
+			continue
+		}
+		memory, err := s.Memory.Bytes()
+		if err != nil {
+			return "", fmt.Errorf("sidecar %s: memory: %w", s.Name, err)
+		}
+		reserved += memory
+	}
+	if reserved == 0 {
+		return p.Memory, nil
+	}
+	memory, err := p.Memory.Bytes()
+	if err != nil {
+		return "", fmt.Errorf("memory: %w", err)
+	}
+	if reserved >= memory {
+		return "", fmt.Errorf("the sidecars of process %s use %s of memory, which exceeds the %s of the process", p.Type, QuantityFromBytes(reserved), p.Memory)
+	}
+	return QuantityFromBytes(memory - reserved), nil
+}
+
+// linkSidecars links each process to the sidecars that run with it and sets the memory they leave to the process.
+// The memory is left unset when the sidecars don't fit in the process, which Validate reports.
+func linkSidecars(app *Application) {
+	for i := range app.Processes {
+		p := &app.Processes[i]
+		sidecars := app.SidecarsOf(p.Type)
+		if len(sidecars) == 0 {
+			continue
+		}
+		p.Sidecars = []string{}
+		for _, s := range sidecars {
+			p.Sidecars = append(p.Sidecars, s.Name)
+		}
+		if memory, err := app.EffectiveMemory(*p); err == nil && memory != p.Memory {
+			p.EffectiveMemory = memory
+		}
+	}
+}
+
+// validateSidecars reports the sidecars that Cloud Foundry rejects: the ones that run with a process type the
+// application does not have, and the ones that don't fit in the memory of their processes. An application without
+// processes runs a single web process.
+func validateSidecars(app Application, errs *ValidationErrors) {
+	if slices.ContainsFunc(app.Processes, func(p ProcessSpec) bool { return p.Type != Web && p.Type != Worker }) {
+		// The sidecars can't be matched to the processes with an invalid type, which are reported by their oneof
+		// constraint.
+		return
+	}
+	for i, s := range app.Sidecars {
+		for j, t := range s.ProcessTypes {
+			if (t != Web && t != Worker) || (len(app.Processes) == 0 && t == Web) {
+				// The unknown process types are reported by their oneof constraint.
+				continue
+			}
+			if !slices.ContainsFunc(app.Processes, func(p ProcessSpec) bool { return p.Type == t }) {
+				*errs = append(*errs, FieldError{Field: fmt.Sprintf("sidecars[%d].processType[%d]", i, j), Rule: "process", Value: t})
+			}
+		}
+	}
+	for i, p := range app.Processes {
+		var reserved int64
+		for _, s := range app.SidecarsOf(p.Type) {
+			// The invalid quantities are reported by their quantity constraint.
+			if memory, err := s.Memory.Bytes(); err == nil && memory > 0 {
+				reserved += memory
+			}
+		}
+		memory, err := p.Memory.Bytes()
+		if reserved > 0 && err == nil && reserved >= memory {
+			*errs = append(*errs, FieldError{Field: fmt.Sprintf("processes[%d].memory", i), Rule: "sidecars=" + string(QuantityFromBytes(reserved)), Value: p.Memory})
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package cloud_foundry
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// sidecarManifest returns a manifest with a web and a worker process and the given sidecars.
+func sidecarManifest(sidecars ...AppManifestSideCar) AppManifest {
+	s := AppManifestSideCars(sidecars)
+	return AppManifest{
+		Name:     "foo",
+		Sidecars: &s,
+		Processes: &AppManifestProcesses{
+			{Type: WebAppProcessType, Memory: "1G"},
+			{Type: WorkerAppProcessType, Memory: "512M"},
+		},
+	}
+}
+
+var _ = Describe("Sidecars", func() {
+	It("links the sidecars to their processes and reports the memory left to them", func() {
+		app, err := Discover(sidecarManifest(
+			AppManifestSideCar{Name: "proxy", ProcessTypes: []AppProcessType{WebAppProcessType}, Command: "./proxy", Memory: "256M"},
+			AppManifestSideCar{Name: "exporter", ProcessTypes: []AppProcessType{WebAppProcessType, WorkerAppProcessType}, Command: "./exporter"},
+		), "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(app.Processes[0].Sidecars).To(Equal([]string{"proxy", "exporter"}))
+		Expect(app.Processes[0].EffectiveMemory).To(Equal(Quantity("768M")))
+		Expect(app.Processes[1].Sidecars).To(Equal([]string{"exporter"}))
+		Expect(app.Processes[1].EffectiveMemory).To(BeEmpty())
+		Expect(app.SidecarsOf(Worker)).To(Equal(Sidecars{app.Sidecars[1]}))
+		Expect(app.EffectiveMemory(app.Processes[1])).To(Equal(Quantity("512M")))
+	})
+
+	It("runs the web sidecars with the implicit web process of an application without processes", func() {
+		s := AppManifestSideCars{{Name: "proxy", ProcessTypes: []AppProcessType{WebAppProcessType}, Command: "./proxy", Memory: "256M"}}
+		app, err := Discover(AppManifest{Name: "foo", Sidecars: &s}, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(app.EffectiveMemory(ProcessSpec{Type: Web, Memory: "1G"})).To(Equal(Quantity("768M")))
+	})
+
+	It("rejects the sidecars that Cloud Foundry rejects", func() {
+		m, err := ReadManifest(testdataDir+"cf_invalid_sidecars.yaml", nil)
+		Expect(err).NotTo(HaveOccurred())
+		errs := []string{}
+		for _, cfApp := range m.Applications {
+			app, err := Discover(*cfApp, m.Version, "")
+			Expect(err).NotTo(HaveOccurred())
+			errs = append(errs, Validate(app).Error())
+		}
+		Expect(errs).To(Equal([]string{
+			`processes[0].memory: must be greater than the 1056M of memory of the sidecars of the process, got "1G"; ` +
+				`processes[1].memory: must be greater than the 800M of memory of the sidecars of the process, got "512M"`,
+			`sidecars[0].processType[1]: no process of type "worker" is defined`,
+		}))
+	})
+
+	It("leaves the memory of the processes unset when the sidecars don't fit", func() {
+		app, err := Discover(sidecarManifest(
+			AppManifestSideCar{Name: "proxy", ProcessTypes: []AppProcessType{WorkerAppProcessType}, Command: "./proxy", Memory: "1G"},
+		), "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(app.Processes[1].Sidecars).To(Equal([]string{"proxy"}))
+		Expect(app.Processes[1].EffectiveMemory).To(BeEmpty())
+		_, err = app.EffectiveMemory(app.Processes[1])
+		Expect(err).To(MatchError("the sidecars of process worker use 1G of memory, which exceeds the 512M of the process"))
+	})
+})
@@ -0,0 +1,222 @@
+package cloud_foundry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces the sensitive values stripped from the recorded responses.
+const redactedValue = "<redacted>"
+
+// sensitiveFields are the fields of the API responses whose values are stripped when redacting a snapshot: the
+// environment variables of the applications and the credentials of the service bindings and of the Docker registries.
+var sensitiveFields = map[string]bool{
+	"env":                true,
+	"environment_json":   true,
+	"credentials":        true,
+	"docker_credentials": true,
+}
+
+// Snapshot is an archive of the responses of the Cloud Controller API recorded during a discovery, which can be
+// replayed later to run the discovery again without access to the API.
+type Snapshot struct {
+	// Endpoint is the URL of the API the responses were recorded from.
+	Endpoint string `json:"endpoint"`
+	// RecordedAt is the time when the recording started.
+	RecordedAt time.Time `json:"recordedAt"`
+	// Redacted is true when the sensitive values were stripped from the responses.
+	Redacted bool `json:"redacted"`
+	// Responses contains the responses in the order they were received.
+	Responses []RecordedResponse `json:"responses"`
+}
+
+// RecordedResponse is a response of the API to a GET request.
+type RecordedResponse struct {
+	// Path is the path and query of the request URL.
+	Path       string `json:"path"`
+	StatusCode int    `json:"statusCode"`
+	Body       string `json:"body"`
+}
+
+// ReadSnapshot reads the snapshot in the file at path.
+func ReadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the snapshot: %w", err)
+	}
+	s := &Snapshot{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("error parsing the snapshot %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// WriteFile writes the snapshot to the file at path. The file is only readable by the owner since it can contain
+// sensitive values.
+func (s *Snapshot) WriteFile(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// Recorder is an http.RoundTripper that records the responses to the GET requests it sends. Other requests, like
+// the ones that obtain tokens from UAA, are sent without being recorded.
+type Recorder struct {
+	// Transport sends the requests. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+
+	mu       sync.Mutex
+	snapshot Snapshot
+}
+
+// NewRecorder returns a recorder for the API at endpoint. When redact is true the values of the sensitive fields are
+// stripped from the recorded responses, while the responses returned to the caller are left untouched.
+func NewRecorder(endpoint string, transport http.RoundTripper, redact bool) *Recorder {
+	return &Recorder{
+		Transport: transport,
+		snapshot:  Snapshot{Endpoint: endpoint, RecordedAt: time.Now().UTC(), Redacted: redact, Responses: []RecordedResponse{}},
+	}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if r.snapshot.Redacted {
+		if body, err = redact(body); err != nil {
+			return nil, fmt.Errorf("error redacting the response of %s: %w", req.URL, err)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snapshot.Responses = append(r.snapshot.Responses, RecordedResponse{
+		Path:       req.URL.RequestURI(),
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+	})
+	return resp, nil
+}
+
+// Snapshot returns the responses recorded so far.
+func (r *Recorder) Snapshot() *Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.snapshot
+	s.Responses = append([]RecordedResponse{}, r.snapshot.Responses...)
+	return &s
+}
+
+// redact strips the values of the sensitive fields from a JSON or YAML body. The keys are kept, since they are
+// useful to understand the configuration of the applications.
+func redact(body []byte) ([]byte, error) {
+	if json.Valid(body) {
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err != nil {
+			return nil, err
+		}
+		return json.Marshal(redactJSON(v, false))
+	}
+	n := yaml.Node{}
+	if err := yaml.Unmarshal(body, &n); err != nil {
+		// Bodies that are neither JSON nor YAML have no fields to redact.
+		return body, nil
+	}
+	redactYAML(&n, false)
+	return yaml.Marshal(&n)
+}
+
+func redactJSON(v interface{}, sensitive bool) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			t[k] = redactJSON(child, sensitive || sensitiveFields[k])
+		}
+		return t
+	case []interface{}:
+		for i, child := range t {
+			t[i] = redactJSON(child, sensitive)
+		}
+		return t
+	case nil:
+		return nil
+	}
+	if sensitive {
+		return redactedValue
+	}
+	return v
+}
+
+func redactYAML(n *yaml.Node, sensitive bool) {
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			redactYAML(c, sensitive)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			redactYAML(n.Content[i+1], sensitive || sensitiveFields[n.Content[i].Value])
+		}
+	case yaml.ScalarNode:
+		if sensitive && n.Tag != "!!null" {
+			n.SetString(redactedValue)
+		}
+	}
+}
+
+// Replayer is an http.RoundTripper that answers the GET requests with the responses in a snapshot, without sending
+// them. When the same request was recorded several times, for instance because it was retried, the last response is
+// used.
+type Replayer struct {
+	responses map[string]RecordedResponse
+}
+
+// NewReplayer returns a replayer for the responses in snapshot.
+func NewReplayer(snapshot *Snapshot) *Replayer {
+	r := &Replayer{responses: map[string]RecordedResponse{}}
+	for _, resp := range snapshot.Responses {
+		r.responses[resp.Path] = resp
+	}
+	return r
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	resp, ok := r.responses[req.URL.RequestURI()]
+	if !ok || req.Method != http.MethodGet {
+		return nil, fmt.Errorf("%s %s was not recorded in the snapshot", req.Method, req.URL.RequestURI())
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+		StatusCode:    resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		Body:          io.NopCloser(bytes.NewBufferString(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}, nil
+}
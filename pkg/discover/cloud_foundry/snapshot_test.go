@@ -0,0 +1,106 @@
+package cloud_foundry
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("API snapshots", func() {
+	var (
+		cc  *fakeCloudController
+		ctx context.Context
+	)
+
+	BeforeEach(func() {
+		cc = newFakeCloudController()
+		ctx = context.Background()
+		cc.registerSpace("org-guid", "my-org", "space-guid", "dev")
+		cc.handle("/v3/apps", url.Values{"space_guids": {"space-guid"}}, http.StatusOK, list("", named("app-1", "foo")))
+		cc.handle("/v3/apps/app-1/manifest", nil, http.StatusOK, `applications:
+- name: foo
+  env:
+    DB_PASSWORD: s3cr3t
+    WORKERS: 4
+  routes:
+  - route: foo.example.com
+`)
+		cc.handle("/v3/service_credential_bindings", url.Values{"app_guids": {"app-1"}, "type": {"app"}}, http.StatusOK,
+			list("", binding("binding-1", "", "si-1")))
+		cc.handle("/v3/service_instances", url.Values{"guids": {"si-1"}}, http.StatusOK, list("", named("si-1", "db")))
+		cc.handle("/v3/service_credential_bindings/binding-1/details", nil, http.StatusServiceUnavailable, "")
+		cc.handle("/v3/service_credential_bindings/binding-1/details", nil, http.StatusOK,
+			map[string]interface{}{"credentials": map[string]interface{}{"username": "admin", "password": "s3cr3t"}})
+	})
+
+	// record discovers the space through a recorder and returns the applications and the snapshot read back from disk.
+	record := func(redact bool) ([]Application, *Snapshot) {
+		client := NewAPIClient(cc.URL, "secret-token")
+		client.RetryBackoff = 0
+		recorder := NewRecorder(cc.URL, nil, redact)
+		client.HTTPClient = &http.Client{Transport: recorder}
+		d := NewAPIDiscoverer(client)
+		d.IncludeCredentials = true
+		apps, err := d.DiscoverSpace(ctx, "my-org", "dev")
+		Expect(err).NotTo(HaveOccurred())
+
+		path := filepath.Join(GinkgoT().TempDir(), "snapshot.json")
+		Expect(recorder.Snapshot().WriteFile(path)).To(Succeed())
+		snapshot, err := ReadSnapshot(path)
+		Expect(err).NotTo(HaveOccurred())
+		return apps, snapshot
+	}
+
+	replay := func(snapshot *Snapshot) ([]Application, error) {
+		client := NewAPIClient(snapshot.Endpoint, "")
+		client.HTTPClient = &http.Client{Transport: NewReplayer(snapshot)}
+		d := NewAPIDiscoverer(client)
+		d.IncludeCredentials = true
+		return d.DiscoverSpace(ctx, "my-org", "dev")
+	}
+
+	It("replays the discovery without sending any request", func() {
+		apps, snapshot := record(false)
+		Expect(snapshot.Endpoint).To(Equal(cc.URL))
+		requests := len(cc.requests)
+		cc.Close()
+
+		replayed, err := replay(snapshot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(replayed).To(Equal(apps))
+		Expect(cc.requests).To(HaveLen(requests))
+	})
+
+	It("never records the access token", func() {
+		_, snapshot := record(false)
+		for _, r := range snapshot.Responses {
+			Expect(r.Body).NotTo(ContainSubstring("secret-token"))
+		}
+	})
+
+	It("strips the env values and the credentials when redacting", func() {
+		apps, snapshot := record(true)
+		Expect(snapshot.Redacted).To(BeTrue())
+		Expect(apps[0].Env).To(Equal(map[string]string{"DB_PASSWORD": "s3cr3t", "WORKERS": "4"}))
+
+		replayed, err := replay(snapshot)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(replayed[0].Env).To(Equal(map[string]string{"DB_PASSWORD": redactedValue, "WORKERS": redactedValue}))
+		Expect(replayed[0].Routes.Routes).To(Equal(apps[0].Routes.Routes))
+		Expect(replayed[0].Services).To(Equal(Services{
+			{Name: "db", Credentials: map[string]interface{}{"username": redactedValue, "password": redactedValue}},
+		}))
+	})
+
+	It("fails the requests that were not recorded", func() {
+		_, snapshot := record(false)
+		client := NewAPIClient(snapshot.Endpoint, "")
+		client.HTTPClient = &http.Client{Transport: NewReplayer(snapshot)}
+		_, err := NewAPIDiscoverer(client).DiscoverSpace(ctx, "my-org", "prod")
+		Expect(err).To(MatchError(ContainSubstring("GET /v3/spaces?names=prod&organization_guids=org-guid was not recorded in the snapshot")))
+	})
+})
@@ -0,0 +1,210 @@
+package cloud_foundry
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type SourceType string
+
+const (
+	// DirectorySourceType represents application source code stored in a directory.
+	DirectorySourceType SourceType = "directory"
+	// ArchiveSourceType represents application source code packaged in a zip, jar or war archive.
+	ArchiveSourceType SourceType = "archive"
+)
+
+// SourceSpec captures the information found in the application source code referenced by the `path` field.
+type SourceSpec struct {
+	// Type captures whether the source code is a directory or an archive.
+	Type SourceType `yaml:"type"`
+	// Languages lists the languages detected from the marker files at the root of the source code,
+	// like `package.json` or `pom.xml`. The list is sorted alphabetically.
+	Languages []string `yaml:"languages,omitempty"`
+	// Procfile captures the commands per process type defined in the `Procfile`, when present.
+	Procfile map[string]string `yaml:"procfile,omitempty"`
+	// CFIgnore captures the patterns in the `.cfignore` file, when present.
+	CFIgnore []string `yaml:"cfignore,omitempty"`
+}
+
+// languageMarkers maps the files that buildpacks use to detect the language of an application.
+var languageMarkers = map[string]string{
+	"pom.xml":          "java",
+	"build.gradle":     "java",
+	"build.gradle.kts": "java",
+	"manifest.mf":      "java",
+	"package.json":     "nodejs",
+	"requirements.txt": "python",
+	"pipfile":          "python",
+	"setup.py":         "python",
+	"pyproject.toml":   "python",
+	"gemfile":          "ruby",
+	"go.mod":           "go",
+	"godeps.json":      "go",
+	"composer.json":    "php",
+	"global.json":      "dotnet",
+	"staticfile":       "static",
+	"index.html":       "static",
+}
+
+// languageExtensions maps the file extensions that identify the language of an application.
+var languageExtensions = map[string]string{
+	".jar":    "java",
+	".war":    "java",
+	".csproj": "dotnet",
+	".fsproj": "dotnet",
+	".vbproj": "dotnet",
+	".php":    "php",
+}
+
+// InspectSource inspects the directory or archive located at the application path and records the language
+// markers, the Procfile and the .cfignore file found in it.
+func InspectSource(appPath string) (*SourceSpec, error) {
+	fi, err := os.Stat(appPath)
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting application path: %w", err)
+	}
+	if fi.IsDir() {
+		return inspectDirectory(appPath)
+	}
+	return inspectArchive(appPath)
+}
+
+func inspectDirectory(dir string) (*SourceSpec, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting application path: %w", err)
+	}
+	names := []string{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "META-INF", "MANIFEST.MF")); err == nil {
+		names = append(names, "MANIFEST.MF")
+	}
+	spec := &SourceSpec{Type: DirectorySourceType, Languages: detectLanguages(names)}
+	if spec.Procfile, err = readOptionalFile(filepath.Join(dir, "Procfile"), parseProcfile); err != nil {
+		return nil, err
+	}
+	if spec.CFIgnore, err = readOptionalFile(filepath.Join(dir, ".cfignore"), parseCFIgnore); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func inspectArchive(archive string) (*SourceSpec, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting application archive %s: %w", archive, err)
+	}
+	defer r.Close()
+	names := []string{filepath.Base(archive)}
+	spec := &SourceSpec{Type: ArchiveSourceType}
+	for _, f := range r.File {
+		name := strings.TrimPrefix(f.Name, "./")
+		if name == "META-INF/MANIFEST.MF" {
+			names = append(names, path.Base(name))
+		}
+		if strings.Contains(name, "/") {
+			// Only the files at the root of the archive are relevant for the detection.
+			continue
+		}
+		names = append(names, name)
+		switch name {
+		case "Procfile":
+			spec.Procfile, err = readZipFile(f, parseProcfile)
+		case ".cfignore":
+			spec.CFIgnore, err = readZipFile(f, parseCFIgnore)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	spec.Languages = detectLanguages(names)
+	return spec, nil
+}
+
+func detectLanguages(names []string) []string {
+	found := map[string]bool{}
+	for _, n := range names {
+		if l, ok := languageMarkers[strings.ToLower(n)]; ok {
+			found[l] = true
+		}
+		if l, ok := languageExtensions[strings.ToLower(filepath.Ext(n))]; ok {
+			found[l] = true
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	languages := make([]string, 0, len(found))
+	for l := range found {
+		languages = append(languages, l)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+func readOptionalFile[T any](name string, parse func(io.Reader) (T, error)) (T, error) {
+	var zero T
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return zero, nil
+	}
+	if err != nil {
+		return zero, err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+func readZipFile[T any](f *zip.File, parse func(io.Reader) (T, error)) (T, error) {
+	var zero T
+	rc, err := f.Open()
+	if err != nil {
+		return zero, err
+	}
+	defer rc.Close()
+	return parse(rc)
+}
+
+// parseProcfile parses the `<process type>: <command>` lines of a Procfile.
+func parseProcfile(r io.Reader) (map[string]string, error) {
+	procs := map[string]string{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		t, cmd, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		procs[strings.TrimSpace(t)] = strings.TrimSpace(cmd)
+	}
+	return procs, s.Err()
+}
+
+// parseCFIgnore returns the patterns listed in a .cfignore file, skipping blank lines and comments.
+func parseCFIgnore(r io.Reader) ([]string, error) {
+	patterns := []string{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, s.Err()
+}
@@ -0,0 +1,101 @@
+package cloud_foundry
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inspect source", func() {
+	writeFiles := func(dir string, files map[string]string) {
+		for name, content := range files {
+			p := filepath.Join(dir, name)
+			Expect(os.MkdirAll(filepath.Dir(p), 0o755)).To(Succeed())
+			Expect(os.WriteFile(p, []byte(content), 0o644)).To(Succeed())
+		}
+	}
+
+	When("the path is a directory", func() {
+		It("records the language markers, the Procfile and the .cfignore", func() {
+			dir := GinkgoT().TempDir()
+			writeFiles(dir, map[string]string{
+				"package.json":  "{}",
+				"Procfile":      "# processes\nweb: node server.js\nworker: node worker.js --queue=jobs\n",
+				".cfignore":     "node_modules\n\n# logs\n*.log\n",
+				"lib/pom.xml":   "",
+				"lib/index.php": "",
+			})
+			spec, err := InspectSource(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec).To(Equal(&SourceSpec{
+				Type:      DirectorySourceType,
+				Languages: []string{"nodejs"},
+				Procfile:  map[string]string{"web": "node server.js", "worker": "node worker.js --queue=jobs"},
+				CFIgnore:  []string{"node_modules", "*.log"},
+			}))
+		})
+
+		It("returns an empty specification when there are no markers", func() {
+			spec, err := InspectSource(GinkgoT().TempDir())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec).To(Equal(&SourceSpec{Type: DirectorySourceType}))
+		})
+	})
+
+	When("the path is an archive", func() {
+		It("inspects the files at the root of the archive", func() {
+			archive := filepath.Join(GinkgoT().TempDir(), "app.jar")
+			f, err := os.Create(archive)
+			Expect(err).NotTo(HaveOccurred())
+			w := zip.NewWriter(f)
+			for name, content := range map[string]string{
+				"META-INF/MANIFEST.MF":  "Main-Class: App",
+				"Procfile":              "web: java -jar app.jar",
+				"BOOT-INF/package.json": "{}",
+			} {
+				fw, err := w.Create(name)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = fw.Write([]byte(content))
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(w.Close()).To(Succeed())
+			Expect(f.Close()).To(Succeed())
+
+			spec, err := InspectSource(archive)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec).To(Equal(&SourceSpec{
+				Type:      ArchiveSourceType,
+				Languages: []string{"java"},
+				Procfile:  map[string]string{"web": "java -jar app.jar"},
+			}))
+		})
+
+		It("fails when the file is not a valid archive", func() {
+			archive := filepath.Join(GinkgoT().TempDir(), "app.zip")
+			Expect(os.WriteFile(archive, []byte("not a zip"), 0o644)).To(Succeed())
+			_, err := InspectSource(archive)
+			Expect(err).To(MatchError(ContainSubstring("error inspecting application archive")))
+		})
+	})
+
+	When("the path does not exist", func() {
+		It("returns an error", func() {
+			_, err := InspectSource(filepath.Join(GinkgoT().TempDir(), "missing"))
+			Expect(err).To(MatchError(os.ErrNotExist))
+		})
+	})
+
+	When("reading a manifest", func() {
+		It("resolves the application path relative to the manifest", func() {
+			m, err := ReadManifest(testdataDir+"cf_complex_example.yaml", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Applications[0].Path).To(Equal(filepath.Join(testdataDir, "my-web-app")))
+			app, err := Discover(*m.Applications[0], m.Version, m.Space)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(app.Path).To(Equal(filepath.Join(testdataDir, "my-web-app")))
+		})
+	})
+})
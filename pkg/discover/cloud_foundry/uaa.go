@@ -0,0 +1,252 @@
+package cloud_foundry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultUAAClientID is the UAA client used by the CF CLI for user logins.
+	DefaultUAAClientID = "cf"
+	// tokenExpiryMargin is subtracted from the expiration of the tokens so that they are refreshed before they expire.
+	tokenExpiryMargin = 30 * time.Second
+)
+
+// TokenSource provides the access tokens used to authenticate the requests to the Cloud Controller API.
+type TokenSource interface {
+	// Token returns a valid access token.
+	Token(ctx context.Context) (string, error)
+}
+
+// invalidator is implemented by the token sources that can discard their current token, so that a new one is
+// obtained when the API rejects it.
+type invalidator interface {
+	Invalidate()
+}
+
+// StaticToken is a TokenSource that always returns the same access token.
+type StaticToken string
+
+func (t StaticToken) Token(context.Context) (string, error) {
+	return trimBearer(string(t)), nil
+}
+
+// Token contains the tokens issued by UAA. Its string representation does not include the tokens so that they are
+// not leaked in logs or in the output.
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	Expiry       time.Time
+}
+
+func (t *Token) String() string {
+	return fmt.Sprintf("Token{Expiry: %s}", t.Expiry.Format(time.RFC3339))
+}
+
+func (t *Token) GoString() string {
+	return t.String()
+}
+
+// valid returns true when the access token exists and is not about to expire.
+func (t *Token) valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || now.Add(tokenExpiryMargin).Before(t.Expiry))
+}
+
+// UAAClient requests tokens from the UAA server of a Cloud Foundry foundation.
+type UAAClient struct {
+	// Endpoint is the base URL of the UAA server, e.g. `https://uaa.sys.example.com`.
+	Endpoint string
+	// ClientID is the OAuth client used to request the tokens. Defaults to DefaultUAAClientID.
+	ClientID string
+	// ClientSecret is the secret of the OAuth client. The client used by the CF CLI has no secret.
+	ClientSecret string
+	// HTTPClient is the client used to send the requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// UAAError is returned when UAA rejects a token request.
+type UAAError struct {
+	StatusCode  int
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *UAAError) Error() string {
+	msg := fmt.Sprintf("UAA token request failed with status code %d", e.StatusCode)
+	if e.Code != "" {
+		msg += ": " + e.Code
+	}
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+	return msg
+}
+
+// FindUAAEndpoint returns the UAA endpoint advertised by the Cloud Controller API at apiEndpoint. It uses the links in
+// the root endpoint and falls back to the `/v2/info` endpoint of older foundations.
+func FindUAAEndpoint(ctx context.Context, apiEndpoint string, httpClient *http.Client) (string, error) {
+	c := &APIClient{Endpoint: strings.TrimSuffix(apiEndpoint, "/"), HTTPClient: httpClient}
+	root := struct {
+		Links map[string]*struct {
+			Href string `json:"href"`
+		} `json:"links"`
+	}{}
+	if err := c.get(ctx, "/", nil, &root); err == nil {
+		for _, name := range []string{"uaa", "login"} {
+			if l := root.Links[name]; l != nil && l.Href != "" {
+				return strings.TrimSuffix(l.Href, "/"), nil
+			}
+		}
+	}
+	info := struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}{}
+	if err := c.get(ctx, "/v2/info", nil, &info); err != nil {
+		return "", fmt.Errorf("error finding the UAA endpoint: %w", err)
+	}
+	if info.TokenEndpoint == "" {
+		return "", errors.New("error finding the UAA endpoint: the API does not advertise any")
+	}
+	return strings.TrimSuffix(info.TokenEndpoint, "/"), nil
+}
+
+// Grant requests a new token from UAA.
+type Grant func(ctx context.Context, uaa *UAAClient) (*Token, error)
+
+// PasswordGrant authenticates a user with username and password.
+func PasswordGrant(username, password string) Grant {
+	return func(ctx context.Context, uaa *UAAClient) (*Token, error) {
+		return uaa.requestToken(ctx, url.Values{"grant_type": {"password"}, "username": {username}, "password": {password}})
+	}
+}
+
+// PasscodeGrant authenticates a user with a one-time passcode obtained from the SSO login page of UAA.
+func PasscodeGrant(passcode string) Grant {
+	return func(ctx context.Context, uaa *UAAClient) (*Token, error) {
+		return uaa.requestToken(ctx, url.Values{"grant_type": {"password"}, "passcode": {passcode}})
+	}
+}
+
+// ClientCredentialsGrant authenticates the OAuth client configured in the UAAClient.
+func ClientCredentialsGrant() Grant {
+	return func(ctx context.Context, uaa *UAAClient) (*Token, error) {
+		return uaa.requestToken(ctx, url.Values{"grant_type": {"client_credentials"}})
+	}
+}
+
+// RefreshTokenGrant obtains a new access token with a refresh token.
+func RefreshTokenGrant(refreshToken string) Grant {
+	return func(ctx context.Context, uaa *UAAClient) (*Token, error) {
+		return uaa.requestToken(ctx, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refreshToken}})
+	}
+}
+
+func (u *UAAClient) requestToken(ctx context.Context, form url.Values) (*Token, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(u.Endpoint, "/")+"/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	clientID := u.ClientID
+	if clientID == "" {
+		clientID = DefaultUAAClientID
+	}
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(u.ClientSecret))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	client := u.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the UAA token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		uaaErr := &UAAError{StatusCode: resp.StatusCode}
+		_ = json.Unmarshal(body, uaaErr)
+		return nil, uaaErr
+	}
+	r := struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}{}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("error decoding the UAA token response: %w", err)
+	}
+	if r.AccessToken == "" {
+		return nil, errors.New("the UAA token response contains no access token")
+	}
+	t := &Token{AccessToken: r.AccessToken, RefreshToken: r.RefreshToken}
+	if r.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(r.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
+
+// UAATokenSource is a TokenSource that obtains the tokens from UAA and refreshes them transparently before they
+// expire. It is safe for concurrent use.
+type UAATokenSource struct {
+	uaa   *UAAClient
+	grant Grant
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewUAATokenSource returns a TokenSource that uses grant to obtain the first token. Subsequent tokens are obtained
+// with the refresh token, falling back to grant when there is no refresh token or it is rejected.
+func NewUAATokenSource(uaa *UAAClient, grant Grant) *UAATokenSource {
+	return &UAATokenSource{uaa: uaa, grant: grant}
+}
+
+// NewUAATokenSourceFromToken returns a TokenSource that starts with an existing token, like the one stored by
+// the CF CLI, and refreshes it with its refresh token.
+func NewUAATokenSourceFromToken(uaa *UAAClient, token *Token) *UAATokenSource {
+	return &UAATokenSource{uaa: uaa, grant: RefreshTokenGrant(token.RefreshToken), token: token}
+}
+
+func (s *UAATokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token.valid(time.Now()) {
+		return s.token.AccessToken, nil
+	}
+	var t *Token
+	var err error
+	if s.token != nil && s.token.RefreshToken != "" {
+		t, err = RefreshTokenGrant(s.token.RefreshToken)(ctx, s.uaa)
+	}
+	if t == nil {
+		if t, err = s.grant(ctx, s.uaa); err != nil {
+			return "", fmt.Errorf("error obtaining an access token: %w", err)
+		}
+	}
+	if t.RefreshToken == "" && s.token != nil {
+		// UAA does not always issue a new refresh token, in which case the current one is still valid.
+		t.RefreshToken = s.token.RefreshToken
+	}
+	s.token = t
+	return t.AccessToken, nil
+}
+
+// Invalidate discards the current access token so that a new one is obtained on the next call to Token.
+func (s *UAATokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != nil {
+		s.token.AccessToken = ""
+	}
+}
@@ -0,0 +1,173 @@
+package cloud_foundry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeUAA is a stand-in of the UAA token endpoint that issues numbered tokens.
+type fakeUAA struct {
+	*httptest.Server
+	mu        sync.Mutex
+	expiresIn int
+	issued    int
+	grants    []url.Values
+	clients   []string
+}
+
+func newFakeUAA(expiresIn int) *fakeUAA {
+	f := &fakeUAA{expiresIn: expiresIn}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	DeferCleanup(f.Close)
+	return f
+}
+
+func (f *fakeUAA) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Method != http.MethodPost || r.URL.Path != "/oauth/token" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	Expect(r.ParseForm()).To(Succeed())
+	clientID, clientSecret, _ := r.BasicAuth()
+	f.clients = append(f.clients, clientID+":"+clientSecret)
+	f.grants = append(f.grants, r.PostForm)
+	form := r.PostForm
+	valid := false
+	switch form.Get("grant_type") {
+	case "password":
+		valid = (form.Get("username") == "admin" && form.Get("password") == "s3cr3t") || form.Get("passcode") == "one-time"
+	case "client_credentials":
+		valid = clientID == "discovery" && clientSecret == "client-s3cr3t"
+	case "refresh_token":
+		valid = form.Get("refresh_token") != "revoked"
+	}
+	if !valid {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"unauthorized","error_description":"Bad credentials"}`))
+		return
+	}
+	f.issued++
+	body := map[string]interface{}{
+		"access_token":  fmt.Sprintf("access-%d", f.issued),
+		"refresh_token": fmt.Sprintf("refresh-%d", f.issued),
+		"expires_in":    f.expiresIn,
+	}
+	if form.Get("grant_type") == "client_credentials" {
+		delete(body, "refresh_token")
+	}
+	Expect(json.NewEncoder(w).Encode(body)).To(Succeed())
+}
+
+var _ = Describe("UAA authentication", func() {
+	var (
+		uaa    *fakeUAA
+		client *UAAClient
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		uaa = newFakeUAA(3600)
+		client = &UAAClient{Endpoint: uaa.URL}
+		ctx = context.Background()
+	})
+
+	DescribeTable("obtains a token with each grant type",
+		func(clientID, clientSecret string, grant Grant, expectedClient string, expectedForm url.Values) {
+			client.ClientID, client.ClientSecret = clientID, clientSecret
+			token, err := NewUAATokenSource(client, grant).Token(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(Equal("access-1"))
+			Expect(uaa.clients).To(Equal([]string{expectedClient}))
+			Expect(uaa.grants).To(Equal([]url.Values{expectedForm}))
+		},
+		Entry("password", "", "", PasswordGrant("admin", "s3cr3t"), "cf:",
+			url.Values{"grant_type": {"password"}, "username": {"admin"}, "password": {"s3cr3t"}}),
+		Entry("SSO passcode", "", "", PasscodeGrant("one-time"), "cf:",
+			url.Values{"grant_type": {"password"}, "passcode": {"one-time"}}),
+		Entry("client credentials", "discovery", "client-s3cr3t", ClientCredentialsGrant(), "discovery:client-s3cr3t",
+			url.Values{"grant_type": {"client_credentials"}}),
+		Entry("refresh token", "", "", RefreshTokenGrant("refresh-0"), "cf:",
+			url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"refresh-0"}}),
+	)
+
+	It("reuses the token until it is about to expire", func() {
+		source := NewUAATokenSource(client, PasswordGrant("admin", "s3cr3t"))
+		for i := 0; i < 3; i++ {
+			Expect(source.Token(ctx)).To(Equal("access-1"))
+		}
+		Expect(uaa.grants).To(HaveLen(1))
+	})
+
+	It("refreshes an expired token with the refresh token", func() {
+		uaa.expiresIn = 1
+		source := NewUAATokenSource(client, PasswordGrant("admin", "s3cr3t"))
+		Expect(source.Token(ctx)).To(Equal("access-1"))
+		Expect(source.Token(ctx)).To(Equal("access-2"))
+		Expect(uaa.grants[1]).To(Equal(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"refresh-1"}}))
+	})
+
+	It("requests a new token when the refresh token is rejected", func() {
+		source := NewUAATokenSourceFromToken(client, &Token{AccessToken: "stale", RefreshToken: "revoked"})
+		source.Invalidate()
+		_, err := source.Token(ctx)
+		Expect(err).To(MatchError("error obtaining an access token: UAA token request failed with status code 401: unauthorized: Bad credentials"))
+	})
+
+	It("never includes the secrets in the errors or in the string form of the tokens", func() {
+		_, err := NewUAATokenSource(client, PasswordGrant("admin", "wrong-password")).Token(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).NotTo(ContainSubstring("wrong-password"))
+
+		token := &Token{AccessToken: "access-secret", RefreshToken: "refresh-secret"}
+		Expect(fmt.Sprintf("%v %+v %#v", token, token, token)).NotTo(ContainSubstring("secret"))
+	})
+
+	It("re-authenticates the API requests rejected with a 401 status code", func() {
+		cc := newFakeCloudController()
+		cc.handle("/v3/organizations", url.Values{"names": {"my-org"}}, http.StatusUnauthorized, `{"errors":[{"code":1000,"title":"CF-InvalidAuthToken","detail":"Invalid Auth Token"}]}`)
+		cc.handle("/v3/organizations", url.Values{"names": {"my-org"}}, http.StatusOK, list("", named("org-guid", "my-org")))
+		cc.handle("/v3/spaces", url.Values{"names": {"dev"}, "organization_guids": {"org-guid"}}, http.StatusOK, list(""))
+		api := NewAPIClient(cc.URL, "")
+		api.Tokens = NewUAATokenSource(client, PasswordGrant("admin", "s3cr3t"))
+
+		_, err := NewAPIDiscoverer(api).DiscoverSpace(ctx, "my-org", "dev")
+		Expect(err).To(MatchError("space dev not found in organization my-org"))
+		Expect(cc.requests[1].URL.Path).To(Equal("/v3/organizations"))
+		Expect(cc.requests[1].Header.Get("Authorization")).To(Equal("bearer access-1"))
+		Expect(cc.requests[2].Header.Get("Authorization")).To(Equal("bearer access-2"))
+	})
+
+	DescribeTable("finds the UAA endpoint advertised by the API",
+		func(root, info interface{}, expected string) {
+			cc := newFakeCloudController()
+			delete(cc.responses, "/")
+			if root != nil {
+				cc.handle("/", nil, http.StatusOK, root)
+			}
+			if info != nil {
+				cc.handle("/v2/info", nil, http.StatusOK, info)
+			}
+			Expect(FindUAAEndpoint(ctx, cc.URL, nil)).To(Equal(expected))
+		},
+		Entry("from the uaa link of the root endpoint",
+			map[string]interface{}{"links": map[string]interface{}{
+				"uaa":   map[string]interface{}{"href": "https://uaa.example.com"},
+				"login": map[string]interface{}{"href": "https://login.example.com"},
+			}}, nil, "https://uaa.example.com"),
+		Entry("from the login link of the root endpoint",
+			map[string]interface{}{"links": map[string]interface{}{"login": map[string]interface{}{"href": "https://login.example.com/"}}},
+			nil, "https://login.example.com"),
+		Entry("from the v2 info endpoint",
+			nil, map[string]interface{}{"token_endpoint": "https://uaa.example.com"}, "https://uaa.example.com"),
+	)
+})
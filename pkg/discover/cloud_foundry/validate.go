@@ -0,0 +1,193 @@
+package cloud_foundry
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FieldError describes a field of the Application that does not satisfy one of its `validate` constraints.
+type FieldError struct {
+	// Field is the path to the field using the YAML field names, e.g. `processes[1].readinessCheck.type`.
+	Field string
+	// Rule is the constraint that failed, e.g. `oneof=http process port`.
+	Rule string
+	// Value is the value of the field.
+	Value interface{}
+}
+
+func (e FieldError) Error() string {
+	name, param, _ := strings.Cut(e.Rule, "=")
+	switch name {
+	case "required":
+		return fmt.Sprintf("%s: is required", e.Field)
+	case "oneof":
+		return fmt.Sprintf("%s: must be one of [%s], got %q", e.Field, param, e.Value)
+	case "min":
+		return fmt.Sprintf("%s: must be greater than or equal to %s, got %v", e.Field, param, e.Value)
+	case "max":
+		return fmt.Sprintf("%s: must be less than or equal to %s, got %v", e.Field, param, e.Value)
+	case "quantity":
+		return fmt.Sprintf("%s: must be an amount like 512M or 1G, got %q", e.Field, e.Value)
+	case "process":
+		return fmt.Sprintf("%s: no process of type %q is defined", e.Field, e.Value)
+	case "sidecars":
+		return fmt.Sprintf("%s: must be greater than the %s of memory of the sidecars of the process, got %q", e.Field, param, e.Value)
+	}
+	return fmt.Sprintf("%s: failed constraint %s with value %v", e.Field, e.Rule, e.Value)
+}
+
+// ValidationErrors contains all the constraint violations found in an Application.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate evaluates the constraints defined in the `validate` tags of the Application and returns a
+// ValidationErrors with every violation found, or nil when the Application is valid.
+//
+// The supported constraints are `required`, `oneof`, `min`, `max`, `quantity`, which checks that the value
+// is a valid Quantity and only accepts the unlimited `-1` with `quantity=unlimited`, and `omitempty`, which
+// skips the rest of the constraints when the field has its zero value. Constraints on slices of scalar values
+// apply to each element, with the exception of `required` which checks that the slice is not empty. Nested
+// structures that are optional and have their zero value are not validated, unlike the inlined ones, whose fields
+// are validated as fields of the parent.
+//
+// Validate also rejects the sidecars that Cloud Foundry rejects: the ones that run with a process type the
+// application does not have, and the ones that use all the memory of their processes.
+func Validate(app Application) error {
+	errs := ValidationErrors{}
+	validateStruct(reflect.ValueOf(app), "", &errs)
+	validateSidecars(app, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, path string, errs *ValidationErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, inline := yamlFieldName(f)
+		if name == "-" {
+			continue
+		}
+		if inline && f.Type.Kind() == reflect.Struct {
+			// The fields of the inlined structures belong to the parent, so their constraints are checked even
+			// when the structure is empty, and reported with their own path.
+			validateStruct(v.Field(i), path, errs)
+			continue
+		}
+		fieldPath := path
+		if !inline {
+			fieldPath = joinFieldPath(path, name)
+		}
+		validateField(v.Field(i), fieldPath, f.Tag.Get("validate"), errs)
+	}
+}
+
+func validateField(v reflect.Value, path, tag string, errs *ValidationErrors) {
+	rules := []string{}
+	if tag != "" {
+		rules = strings.Split(tag, ",")
+	}
+	if v.IsZero() {
+		if slices.Contains(rules, "required") {
+			*errs = append(*errs, FieldError{Field: path, Rule: "required", Value: v.Interface()})
+			return
+		}
+		switch v.Kind() {
+		case reflect.Struct, reflect.Pointer, reflect.Slice, reflect.Map:
+			// Optional structures and lists that are not set have nothing else to validate.
+			return
+		}
+		if slices.Contains(rules, "omitempty") {
+			return
+		}
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		validateField(v.Elem(), path, tag, errs)
+	case reflect.Struct:
+		validateStruct(v, path, errs)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			e := v.Index(i)
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			if e.Kind() == reflect.Struct {
+				validateStruct(e, elemPath, errs)
+				continue
+			}
+			validateRules(e, elemPath, rules, errs)
+		}
+	default:
+		validateRules(v, path, rules, errs)
+	}
+}
+
+func validateRules(v reflect.Value, path string, rules []string, errs *ValidationErrors) {
+	for _, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		ok := true
+		switch name {
+		case "oneof":
+			ok = slices.Contains(strings.Fields(param), fmt.Sprint(v.Interface()))
+		case "quantity":
+			// Cloud Foundry only accepts -1, which means unlimited, for the quantities marked with `unlimited`.
+			q, err := ParseQuantity(v.String())
+			bytes, _ := q.Bytes()
+			ok = err == nil && (bytes >= 0 || param == "unlimited")
+		case "min", "max":
+			limit, err := strconv.ParseInt(param, 10, 64)
+			if err != nil || !isInt(v) {
+				continue
+			}
+			if name == "min" {
+				ok = v.Int() >= limit
+			} else {
+				ok = v.Int() <= limit
+			}
+		}
+		if !ok {
+			*errs = append(*errs, FieldError{Field: path, Rule: rule, Value: v.Interface()})
+		}
+	}
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+// yamlFieldName returns the name of the field in the YAML representation and whether it is inlined.
+func yamlFieldName(f reflect.StructField) (string, bool) {
+	parts := strings.Split(f.Tag.Get("yaml"), ",")
+	if slices.Contains(parts[1:], "inline") {
+		return parts[0], true
+	}
+	if parts[0] == "" {
+		return strings.ToLower(f.Name), false
+	}
+	return parts[0], false
+}
+
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
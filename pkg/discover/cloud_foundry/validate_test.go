@@ -0,0 +1,128 @@
+package cloud_foundry
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate application", func() {
+	validApp := func() Application {
+		app, err := Discover(AppManifest{
+			Name: "foo",
+			Routes: &AppManifestRoutes{
+				{Route: "foo.example.com", Protocol: HTTP2, Options: &AppRouteOptions{LoadBalancing: "least-connection"}},
+			},
+			Processes: &AppManifestProcesses{
+				{Type: WebAppProcessType, Lifecycle: "buildpack"},
+				{Type: WorkerAppProcessType},
+			},
+			Sidecars: &AppManifestSideCars{
+				{Name: "bar", Command: "run", ProcessTypes: []AppProcessType{WebAppProcessType}},
+			},
+		}, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		return app
+	}
+
+	When("the application satisfies all the constraints", func() {
+		It("returns no error", func() {
+			Expect(Validate(validApp())).To(Succeed())
+		})
+	})
+
+	When("the application violates the constraints", func() {
+		DescribeTable("reports every violation with its field path", func(override func(*Application), expected ValidationErrors) {
+			app := validApp()
+			override(&app)
+			err := Validate(app)
+			Expect(err).To(Equal(expected))
+		},
+			Entry("with a missing name",
+				func(app *Application) { app.Metadata.Name = "" },
+				ValidationErrors{{Field: "name", Rule: "required", Value: ""}}),
+			Entry("with no metadata",
+				func(app *Application) { app.Metadata = Metadata{} },
+				ValidationErrors{{Field: "name", Rule: "required", Value: ""}}),
+			Entry("with a timeout over the maximum",
+				func(app *Application) { app.Timeout = 181 },
+				ValidationErrors{{Field: "timeout", Rule: "max=180", Value: 181}}),
+			Entry("with no instances",
+				func(app *Application) { app.Instances = 0 },
+				ValidationErrors{{Field: "instances", Rule: "required", Value: 0}}),
+			Entry("with an invalid lifecycle",
+				func(app *Application) { app.Processes[0].Lifecycle = "container" },
+				ValidationErrors{{Field: "processes[0].lifecycle", Rule: "oneof=buildpack cnb docker", Value: LifecycleType("container")}}),
+			Entry("with an invalid readiness check type",
+				func(app *Application) { app.Processes[1].ReadinessCheck.Type = "exec" },
+				ValidationErrors{{Field: "processes[1].readinessCheck.type", Rule: "oneof=http process port", Value: ProbeType("exec")}}),
+			Entry("with an invalid route protocol and load balancing",
+				func(app *Application) {
+					app.Routes.Routes[0].Protocol = "udp"
+					app.Routes.Routes[0].Options.LoadBalancing = "random"
+				},
+				ValidationErrors{
+					{Field: "routes[0].protocol", Rule: "oneof=http1 http2 tcp", Value: RouteProtocol("udp")},
+					{Field: "routes[0].options.loadBalancing", Rule: "oneof=round-robin least-connection", Value: LoadBalancingType("random")},
+				}),
+			Entry("with an invalid sidecar",
+				func(app *Application) {
+					app.Sidecars[0].Command = ""
+					app.Sidecars[0].ProcessTypes = []ProcessType{Web, "clock"}
+				},
+				ValidationErrors{
+					{Field: "sidecars[0].processType[1]", Rule: "oneof=worker web", Value: ProcessType("clock")},
+					{Field: "sidecars[0].command", Rule: "required", Value: ""},
+				}),
+			Entry("with invalid quantities",
+				func(app *Application) {
+					app.Processes[0].Memory = "1 gigabyte"
+					app.Processes[1].DiskQuota = "1024"
+					app.Sidecars[0].Memory = "lots"
+				},
+				ValidationErrors{
+					{Field: "processes[0].memory", Rule: "quantity", Value: Quantity("1 gigabyte")},
+					{Field: "processes[1].disk", Rule: "quantity", Value: Quantity("1024")},
+					{Field: "sidecars[0].memory", Rule: "quantity", Value: Quantity("lots")},
+				}),
+			Entry("with unlimited memory and disk",
+				func(app *Application) {
+					app.Processes[0].Memory = "-1"
+					app.Processes[1].DiskQuota = "-1"
+					app.Sidecars[0].Memory = "-1"
+				},
+				ValidationErrors{
+					{Field: "processes[0].memory", Rule: "quantity", Value: Quantity("-1")},
+					{Field: "processes[1].disk", Rule: "quantity", Value: Quantity("-1")},
+					{Field: "sidecars[0].memory", Rule: "quantity", Value: Quantity("-1")},
+				}),
+			Entry("with a docker image without a pullspec",
+				func(app *Application) { app.Docker.Username = "foo" },
+				ValidationErrors{{Field: "docker.image", Rule: "required", Value: ""}}),
+		)
+
+		It("renders the violations in the error message", func() {
+			app := validApp()
+			app.Timeout = 200
+			app.Processes[0].Type = "clock"
+			Expect(Validate(app)).To(MatchError(`processes[0].type: must be one of [web worker], got "clock"; timeout: must be less than or equal to 180, got 200`))
+		})
+
+		It("points at the name of an application without name", func() {
+			app, err := Discover(AppManifest{}, "", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(Validate(app)).To(MatchError("name: is required"))
+		})
+
+		It("accepts an unlimited log rate", func() {
+			app := validApp()
+			app.Processes[0].LogRateLimit = "-1"
+			Expect(Validate(app)).To(Succeed())
+		})
+
+		It("renders the invalid quantities in the error message", func() {
+			app := validApp()
+			app.Processes[0].LogRateLimit = "16 per second"
+			Expect(Validate(app)).To(MatchError(`processes[0].logRateLimit: must be an amount like 512M or 1G, got "16 per second"`))
+		})
+	})
+})
@@ -0,0 +1,304 @@
+package helm
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	"github.com/gciavarrini/cf-application-discovery/pkg/generate/kubernetes"
+	"gopkg.in/yaml.v3"
+)
+
+// ChartVersion is the version of the generated charts.
+const ChartVersion = "0.1.0"
+
+// templates are the templates shared by all the charts. They render the same resources as the Kubernetes generator
+// from the values of the chart, except for the ServiceBindings: the Deployments mount the binding Secrets themselves.
+//
+//go:embed templates/*.yaml
+var templates embed.FS
+
+// Layout selects how the applications are grouped into charts.
+type Layout string
+
+const (
+	// SpaceLayout generates a chart per space, containing all its applications.
+	SpaceLayout Layout = "space"
+	// ApplicationLayout generates a chart per application.
+	ApplicationLayout Layout = "application"
+)
+
+// Options configures the generation of the charts.
+type Options struct {
+	Layout Layout
+	// Kubernetes configures the translation of the applications into the values of the charts.
+	Kubernetes kubernetes.Options
+}
+
+// Chart is a generated Helm chart.
+type Chart struct {
+	// Name is the name of the chart, which is also the name of its directory.
+	Name string
+	// Files maps the path of each file in the chart, relative to the chart directory, to its contents.
+	Files map[string][]byte
+}
+
+// Result contains the generated charts.
+type Result struct {
+	Charts []Chart
+	// Warnings describe the parts of the applications that need manual changes after the generation.
+	Warnings []string
+}
+
+// values is the content of the values.yaml file of the charts.
+type values struct {
+	Ingress      ingressValues                `yaml:"ingress"`
+	Applications map[string]applicationValues `yaml:"applications"`
+}
+
+type ingressValues struct {
+	// Enabled renders an Ingress for the HTTP routes of each application.
+	Enabled   bool   `yaml:"enabled"`
+	ClassName string `yaml:"className"`
+}
+
+type applicationValues struct {
+	// Image is the container image of the application, or the image to build from its source code when it is
+	// deployed with buildpacks.
+	Image       string            `yaml:"image"`
+	Env         map[string]string `yaml:"env"`
+	Routes      []routeValues     `yaml:"routes"`
+	RandomRoute bool              `yaml:"randomRoute,omitempty"`
+	Services    []serviceValues   `yaml:"services"`
+	Processes   []processValues   `yaml:"processes"`
+}
+
+type routeValues struct {
+	Host     string `yaml:"host"`
+	Path     string `yaml:"path,omitempty"`
+	Port     int    `yaml:"port,omitempty"`
+	Protocol string `yaml:"protocol"`
+}
+
+type serviceValues struct {
+	// Name is the name of the service instance in Cloud Foundry.
+	Name string `yaml:"name"`
+	// Binding is the name of the binding, which is also the directory where the credentials are mounted.
+	Binding string `yaml:"binding"`
+	// Secret is the name of the Secret with the credentials of the binding.
+	Secret string `yaml:"secret"`
+	// Type is the servicebinding.io type of the service.
+	Type string `yaml:"type"`
+	// Credentials are the entries of the Secret other than type and provider. They are empty unless the
+	// application was discovered from the API with the credentials of its bindings.
+	Credentials map[string]string `yaml:"credentials,omitempty"`
+}
+
+type processValues struct {
+	Type      string `yaml:"type"`
+	Name      string `yaml:"name"`
+	Instances int    `yaml:"instances"`
+	Command   string `yaml:"command,omitempty"`
+	// Resources are the requests and limits of the container, derived from the quotas of the process by the
+	// resource policy.
+	Resources      kubernetes.ResourceRequirements `yaml:"resources"`
+	LivenessProbe  *kubernetes.Probe               `yaml:"livenessProbe,omitempty"`
+	ReadinessProbe *kubernetes.Probe               `yaml:"readinessProbe,omitempty"`
+	StartupProbe   *kubernetes.Probe               `yaml:"startupProbe,omitempty"`
+	// Sidecars run in the pods of the process, with the image and env of the application.
+	Sidecars []sidecarValues `yaml:"sidecars,omitempty"`
+}
+
+type sidecarValues struct {
+	Name      string                          `yaml:"name"`
+	Command   string                          `yaml:"command"`
+	Resources kubernetes.ResourceRequirements `yaml:"resources"`
+}
+
+// Generate groups the applications into charts according to the layout in opts. The values of each chart expose the
+// settings of its applications, so that they can be tuned without editing the templates.
+func Generate(apps []discover.Application, opts Options) (*Result, error) {
+	r := &Result{}
+	groups := map[string][]discover.Application{}
+	names := []string{}
+	for _, app := range apps {
+		name := kubernetes.ResourceName(app.Metadata.Name)
+		if opts.Layout != ApplicationLayout {
+			name = "default"
+			if app.Metadata.Space != "" {
+				name = kubernetes.ResourceName(app.Metadata.Space)
+			}
+		}
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], app)
+	}
+	for _, name := range names {
+		v := values{Ingress: ingressValues{Enabled: true, ClassName: opts.Kubernetes.IngressClassName}, Applications: map[string]applicationValues{}}
+		for _, app := range groups[name] {
+			appName := kubernetes.ResourceName(app.Metadata.Name)
+			if _, ok := v.Applications[appName]; ok {
+				return nil, fmt.Errorf("chart %s: application %s is defined more than once", name, app.Metadata.Name)
+			}
+			av, warnings, err := applicationValuesFor(app, opts.Kubernetes)
+			if err != nil {
+				return nil, err
+			}
+			v.Applications[appName] = av
+			r.Warnings = append(r.Warnings, warnings...)
+		}
+		description := fmt.Sprintf("Cloud Foundry application %s", groups[name][0].Metadata.Name)
+		if opts.Layout != ApplicationLayout {
+			description = fmt.Sprintf("Cloud Foundry applications of space %s", groups[name][0].Metadata.Space)
+			if groups[name][0].Metadata.Space == "" {
+				description = "Cloud Foundry applications without space"
+			}
+		}
+		c, err := chart(name, description, v)
+		if err != nil {
+			return nil, err
+		}
+		r.Charts = append(r.Charts, c)
+	}
+	return r, nil
+}
+
+// applicationValuesFor builds the values of the application from the resources generated for it by the Kubernetes
+// generator, so that the charts render the same resources.
+func applicationValuesFor(app discover.Application, opts kubernetes.Options) (applicationValues, []string, error) {
+	k, err := kubernetes.Generate(app, opts)
+	if err != nil {
+		return applicationValues{}, nil, err
+	}
+	v := applicationValues{
+		Image:       kubernetes.Image(app, opts.ImageRegistry),
+		Env:         map[string]string{},
+		Routes:      []routeValues{},
+		RandomRoute: app.Routes.RandomRoute && !app.Routes.NoRoute,
+		Services:    []serviceValues{},
+		Processes:   []processValues{},
+	}
+	for k, val := range app.Env {
+		v.Env[k] = val
+	}
+	if !app.Routes.NoRoute {
+		for _, route := range app.Routes.Routes {
+			host, port, path := kubernetes.SplitRoute(route.Route)
+			protocol := string(route.Protocol)
+			if protocol == "" {
+				protocol = string(discover.HTTPRouteProtocol)
+			}
+			v.Routes = append(v.Routes, routeValues{Host: host, Path: path, Port: port, Protocol: protocol})
+		}
+	}
+	secrets := map[string]*kubernetes.Secret{}
+	for _, o := range k.Objects {
+		if s, ok := o.(*kubernetes.Secret); ok {
+			secrets[s.Metadata.Name] = s
+		}
+	}
+	for _, s := range app.Services {
+		sv := serviceValues{Name: s.Name, Binding: kubernetes.BindingName(s), Secret: kubernetes.BindingSecretName(app, s)}
+		for key, value := range secrets[sv.Secret].StringData {
+			switch key {
+			case "type":
+				sv.Type = value
+			case "provider":
+			default:
+				if sv.Credentials == nil {
+					sv.Credentials = map[string]string{}
+				}
+				sv.Credentials[key] = value
+			}
+		}
+		v.Services = append(v.Services, sv)
+	}
+	// The generated resources start with the Deployment of each process.
+	for i, p := range kubernetes.Processes(app) {
+		d := k.Objects[i].(*kubernetes.Deployment)
+		c := d.Spec.Template.Spec.Containers[0]
+		sidecars := []sidecarValues{}
+		for _, s := range d.Spec.Template.Spec.Containers[1:] {
+			// The sidecars run their command with a shell, like the processes.
+			sidecars = append(sidecars, sidecarValues{Name: s.Name, Command: s.Command[len(s.Command)-1], Resources: s.Resources})
+		}
+		v.Processes = append(v.Processes, processValues{
+			Type:           c.Name,
+			Name:           d.Metadata.Name,
+			Instances:      d.Spec.Replicas,
+			Command:        p.Command,
+			Resources:      c.Resources,
+			LivenessProbe:  c.LivenessProbe,
+			ReadinessProbe: c.ReadinessProbe,
+			StartupProbe:   c.StartupProbe,
+			Sidecars:       sidecars,
+		})
+	}
+	return v, k.Warnings, nil
+}
+
+func chart(name, description string, v values) (Chart, error) {
+	metadata := struct {
+		APIVersion  string `yaml:"apiVersion"`
+		Name        string `yaml:"name"`
+		Description string `yaml:"description"`
+		Type        string `yaml:"type"`
+		Version     string `yaml:"version"`
+	}{APIVersion: "v2", Name: name, Description: description, Type: "application", Version: ChartVersion}
+	c := Chart{Name: name, Files: map[string][]byte{}}
+	var err error
+	if c.Files["Chart.yaml"], err = marshal(metadata); err != nil {
+		return Chart{}, err
+	}
+	if c.Files["values.yaml"], err = marshal(v); err != nil {
+		return Chart{}, err
+	}
+	err = fs.WalkDir(templates, "templates", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := templates.ReadFile(p)
+		c.Files[path.Join("templates", path.Base(p))] = content
+		return err
+	})
+	return c, err
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	b := bytes.Buffer{}
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Write writes the chart into `<dir>/<chart name>`.
+func (c Chart) Write(dir string) error {
+	paths := make([]string, 0, len(c.Files))
+	for p := range c.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		file := filepath.Join(dir, c.Name, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, c.Files[p], 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
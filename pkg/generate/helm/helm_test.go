@@ -0,0 +1,373 @@
+package helm
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	"github.com/gciavarrini/cf-application-discovery/pkg/generate/kubernetes"
+	"github.com/gciavarrini/cf-application-discovery/pkg/output"
+	sprig "github.com/go-task/slim-sprig/v3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+// helmTemplate renders the templates of the chart with its default values the way `helm template` does, using the
+// sprig functions and the toYaml function added by Helm, and returns the rendered documents.
+func helmTemplate(c Chart) []map[string]interface{} {
+	vals := map[string]interface{}{}
+	Expect(yaml.Unmarshal(c.Files["values.yaml"], &vals)).To(Succeed())
+	data := map[string]interface{}{
+		"Values":  vals,
+		"Release": map[string]interface{}{"Name": "release", "Namespace": "default", "Service": "Helm"},
+		"Chart":   map[string]interface{}{"Name": c.Name, "Version": ChartVersion},
+	}
+	funcs := sprig.TxtFuncMap()
+	funcs["toYaml"] = func(v interface{}) string {
+		b := bytes.Buffer{}
+		enc := yaml.NewEncoder(&b)
+		enc.SetIndent(2)
+		Expect(enc.Encode(v)).To(Succeed())
+		return strings.TrimSuffix(b.String(), "\n")
+	}
+
+	files := []string{}
+	for f := range c.Files {
+		if strings.HasPrefix(f, "templates/") {
+			files = append(files, f)
+		}
+	}
+	slices.Sort(files)
+	docs := []map[string]interface{}{}
+	for _, f := range files {
+		t, err := template.New(filepath.Base(f)).Funcs(funcs).Option("missingkey=zero").Parse(string(c.Files[f]))
+		Expect(err).NotTo(HaveOccurred(), f)
+		b := bytes.Buffer{}
+		Expect(t.Execute(&b, data)).To(Succeed(), f)
+		dec := yaml.NewDecoder(&b)
+		for {
+			doc := map[string]interface{}{}
+			err := dec.Decode(&doc)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred(), b.String())
+			if len(doc) > 0 {
+				docs = append(docs, doc)
+			}
+		}
+	}
+	return docs
+}
+
+// normalize removes the fields that only differ between the chart and the Kubernetes generator: the namespace, set
+// by Helm, the labels that identify the release, and the order of the environment variables.
+func normalize(doc map[string]interface{}) map[string]interface{} {
+	metadata := doc["metadata"].(map[string]interface{})
+	delete(metadata, "namespace")
+	labels := metadata["labels"].(map[string]interface{})
+	delete(labels, "app.kubernetes.io/instance")
+	delete(labels, "app.kubernetes.io/managed-by")
+	if doc["kind"] == "Deployment" {
+		spec := doc["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})
+		for _, c := range spec["containers"].([]interface{}) {
+			env := c.(map[string]interface{})["env"].([]interface{})
+			slices.SortFunc(env, func(a, b interface{}) int {
+				return strings.Compare(a.(map[string]interface{})["name"].(string), b.(map[string]interface{})["name"].(string))
+			})
+		}
+	}
+	return doc
+}
+
+// documents returns the objects as generic YAML documents.
+func documents(objects []kubernetes.Object) []map[string]interface{} {
+	b := bytes.Buffer{}
+	Expect(output.Encode(&b, output.YAMLFormat, objects)).To(Succeed())
+	docs := []map[string]interface{}{}
+	dec := yaml.NewDecoder(&b)
+	for {
+		doc := map[string]interface{}{}
+		if err := dec.Decode(&doc); errors.Is(err, io.EOF) {
+			return docs
+		}
+		docs = append(docs, doc)
+	}
+}
+
+func sampleApplications() []discover.Application {
+	health := discover.ProbeSpec{Type: discover.HTTPProbeType, Endpoint: "/health", Timeout: 1, Interval: 30}
+	readiness := discover.ProbeSpec{Type: discover.ProcessProbeType, Endpoint: "/", Timeout: 1, Interval: 30}
+	return []discover.Application{
+		{
+			Metadata: discover.Metadata{Name: "frontend", Space: "dev"},
+			Env:      map[string]string{"LOG_LEVEL": "debug", "API_URL": "https://backend.example.com"},
+			Routes: discover.RouteSpec{Routes: discover.Routes{
+				{Route: "frontend.example.com"},
+				{Route: "www.example.com/app", Protocol: discover.HTTP2RouteProtocol},
+				{Route: "tcp.example.com:1034", Protocol: discover.TCPRouteProtocol},
+			}},
+			Timeout:   90,
+			Instances: 2,
+			Processes: discover.Processes{
+				{Type: discover.Web, Command: "npm start", Memory: "512M", Instances: 2, HealthCheck: health, ReadinessCheck: readiness},
+			},
+		},
+		{
+			Metadata:  discover.Metadata{Name: "backend", Space: "dev"},
+			Routes:    discover.RouteSpec{NoRoute: true},
+			Services:  discover.Services{{Name: "db", BindingName: "orders-db"}, {Name: "cache"}},
+			Timeout:   60,
+			Instances: 1,
+			Docker:    discover.Docker{Image: "registry.example.com/backend:2.1"},
+			Processes: discover.Processes{
+				{Type: discover.Worker, Command: "./worker", Memory: "1G", Instances: 3, HealthCheck: readiness, ReadinessCheck: readiness},
+			},
+		},
+		{
+			Metadata:  discover.Metadata{Name: "reports", Space: "prod"},
+			Timeout:   60,
+			Instances: 1,
+			Sidecars: discover.Sidecars{
+				{Name: "exporter", Command: "./exporter --port 9100", ProcessTypes: []discover.ProcessType{discover.Web}, Memory: "64M"},
+			},
+		},
+	}
+}
+
+var _ = Describe("Helm generator", func() {
+	It("generates a chart per space", func() {
+		r, err := Generate(sampleApplications(), Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Charts).To(HaveLen(2))
+		Expect(r.Charts[0].Name).To(Equal("dev"))
+		Expect(r.Charts[1].Name).To(Equal("prod"))
+		Expect(string(r.Charts[0].Files["Chart.yaml"])).To(Equal(`apiVersion: v2
+name: dev
+description: Cloud Foundry applications of space dev
+type: application
+version: 0.1.0
+`))
+		Expect(r.Charts[0].Files).To(HaveKey("templates/deployment.yaml"))
+		Expect(r.Charts[0].Files).To(HaveKey("templates/service.yaml"))
+		Expect(r.Charts[0].Files).To(HaveKey("templates/ingress.yaml"))
+		Expect(r.Charts[0].Files).To(HaveKey("templates/secret.yaml"))
+		Expect(r.Warnings).To(ContainElement(ContainSubstring("process worker of application backend uses a process health check")))
+	})
+
+	It("generates a chart per application", func() {
+		r, err := Generate(sampleApplications(), Options{Layout: ApplicationLayout})
+		Expect(err).NotTo(HaveOccurred())
+		names := []string{}
+		for _, c := range r.Charts {
+			names = append(names, c.Name)
+		}
+		Expect(names).To(Equal([]string{"frontend", "backend", "reports"}))
+		Expect(string(r.Charts[0].Files["Chart.yaml"])).To(ContainSubstring("description: Cloud Foundry application frontend\n"))
+	})
+
+	It("exposes the settings of the applications in the values", func() {
+		r, err := Generate(sampleApplications()[:2], Options{Kubernetes: kubernetes.Options{ImageRegistry: "quay.io/team"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(r.Charts[0].Files["values.yaml"])).To(Equal(`ingress:
+  enabled: true
+  className: ""
+applications:
+  backend:
+    image: registry.example.com/backend:2.1
+    env: {}
+    routes: []
+    services:
+      - name: db
+        binding: orders-db
+        secret: backend-orders-db
+        type: db
+      - name: cache
+        binding: cache
+        secret: backend-cache
+        type: cache
+    processes:
+      - type: worker
+        name: backend-worker
+        instances: 3
+        command: ./worker
+        resources:
+          limits:
+            ephemeral-storage: 1Gi
+            memory: 1Gi
+          requests:
+            cpu: 125m
+            ephemeral-storage: 1Gi
+            memory: 1Gi
+  frontend:
+    image: quay.io/team/frontend:latest
+    env:
+      API_URL: https://backend.example.com
+      LOG_LEVEL: debug
+    routes:
+      - host: frontend.example.com
+        protocol: http1
+      - host: www.example.com
+        path: /app
+        protocol: http2
+      - host: tcp.example.com
+        port: 1034
+        protocol: tcp
+    services: []
+    processes:
+      - type: web
+        name: frontend
+        instances: 2
+        command: npm start
+        resources:
+          limits:
+            ephemeral-storage: 1Gi
+            memory: 512Mi
+          requests:
+            cpu: 63m
+            ephemeral-storage: 1Gi
+            memory: 512Mi
+        livenessProbe:
+          httpGet:
+            path: /health
+            port: 8080
+          periodSeconds: 30
+          timeoutSeconds: 1
+          failureThreshold: 1
+        startupProbe:
+          httpGet:
+            path: /health
+            port: 8080
+          periodSeconds: 2
+          timeoutSeconds: 1
+          failureThreshold: 45
+`))
+	})
+
+	It("renders the same workloads as the Kubernetes generator with the default values", func() {
+		apps := sampleApplications()
+		r, err := Generate(apps, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		rendered := map[string]map[string]interface{}{}
+		for _, c := range r.Charts {
+			for _, doc := range helmTemplate(c) {
+				rendered[doc["kind"].(string)+"/"+doc["metadata"].(map[string]interface{})["name"].(string)] = doc
+			}
+		}
+		Expect(rendered).To(HaveLen(7))
+
+		for _, app := range []discover.Application{apps[0], apps[2]} {
+			k, err := kubernetes.Generate(app, kubernetes.Options{})
+			Expect(err).NotTo(HaveOccurred())
+			for _, doc := range documents(k.Objects) {
+				key := doc["kind"].(string) + "/" + doc["metadata"].(map[string]interface{})["name"].(string)
+				Expect(rendered).To(HaveKey(key))
+				Expect(normalize(rendered[key])).To(Equal(normalize(doc)), key)
+			}
+		}
+	})
+
+	It("only renders a Service and an Ingress for the applications with a web process", func() {
+		apps := sampleApplications()[1:2]
+		apps[0].Routes = discover.RouteSpec{Routes: discover.Routes{{Route: "backend.example.com"}}}
+		r, err := Generate(apps, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		k, err := kubernetes.Generate(apps[0], kubernetes.Options{})
+		Expect(err).NotTo(HaveOccurred())
+		kinds := func(docs []map[string]interface{}) []string {
+			kinds := []string{}
+			for _, doc := range docs {
+				kinds = append(kinds, doc["kind"].(string))
+			}
+			slices.Sort(kinds)
+			return kinds
+		}
+		Expect(kinds(helmTemplate(r.Charts[0]))).To(Equal([]string{"Deployment", "Secret", "Secret"}))
+		Expect(kinds(documents(k.Objects))).To(Equal([]string{"Deployment", "Secret", "Secret", "ServiceBinding", "ServiceBinding"}))
+	})
+
+	It("renders the Secrets of the service bindings with their credentials", func() {
+		apps := sampleApplications()[1:2]
+		apps[0].Services[1].Credentials = map[string]interface{}{"uri": "rediss://cache.example.com:6380", "port": 6380}
+		r, err := Generate(apps, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		rendered := map[string]map[string]interface{}{}
+		for _, doc := range helmTemplate(r.Charts[0]) {
+			rendered[doc["kind"].(string)+"/"+doc["metadata"].(map[string]interface{})["name"].(string)] = doc
+		}
+		k, err := kubernetes.Generate(apps[0], kubernetes.Options{})
+		Expect(err).NotTo(HaveOccurred())
+		secrets := 0
+		for _, doc := range documents(k.Objects) {
+			if doc["kind"] != "Secret" {
+				continue
+			}
+			key := "Secret/" + doc["metadata"].(map[string]interface{})["name"].(string)
+			Expect(rendered).To(HaveKey(key))
+			Expect(normalize(rendered[key])).To(Equal(normalize(doc)), key)
+			secrets++
+		}
+		Expect(secrets).To(Equal(2))
+		Expect(rendered["Secret/backend-cache"]["stringData"]).To(HaveKeyWithValue("type", "redis"))
+	})
+
+	It("renders an Ingress for the HTTP routes and mounts the service bindings", func() {
+		r, err := Generate(sampleApplications()[:2], Options{})
+		Expect(err).NotTo(HaveOccurred())
+		docs := helmTemplate(r.Charts[0])
+		b := bytes.Buffer{}
+		Expect(output.Encode(&b, output.YAMLFormat, docs)).To(Succeed())
+		Expect(b.String()).To(ContainSubstring(`kind: Ingress
+metadata:
+  labels:
+    app.kubernetes.io/component: web
+    app.kubernetes.io/instance: release
+    app.kubernetes.io/managed-by: Helm
+    app.kubernetes.io/name: frontend
+  name: frontend
+spec:
+  rules:
+    - host: frontend.example.com
+      http:
+        paths:
+          - backend:
+              service:
+                name: frontend
+                port:
+                  name: http
+            path: /
+            pathType: Prefix
+    - host: www.example.com
+      http:
+        paths:
+          - backend:
+              service:
+                name: frontend
+                port:
+                  name: http
+            path: /app
+            pathType: Prefix
+`))
+		Expect(b.String()).To(ContainSubstring(`          volumeMounts:
+            - mountPath: /bindings/orders-db
+              name: binding-0
+              readOnly: true
+            - mountPath: /bindings/cache
+              name: binding-1
+              readOnly: true
+      volumes:
+        - name: binding-0
+          secret:
+            secretName: backend-orders-db
+        - name: binding-1
+          secret:
+            secretName: backend-cache
+`))
+	})
+})
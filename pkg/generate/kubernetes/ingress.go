@@ -0,0 +1,40 @@
+package kubernetes
+
+import (
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+)
+
+// ingress returns an Ingress that sends the traffic of the HTTP routes of the application to its Service, or nil
+// when the application has no HTTP routes. TCP routes can't be exposed with an Ingress.
+func ingress(app discover.Application, namespace string, opts Options) *Ingress {
+	if app.Routes.NoRoute {
+		return nil
+	}
+	name := ResourceName(app.Metadata.Name)
+	rules := []IngressRule{}
+	for _, route := range app.Routes.Routes {
+		if route.Protocol == discover.TCPRouteProtocol {
+			continue
+		}
+		host, _, path := SplitRoute(route.Route)
+		if path == "" {
+			path = "/"
+		}
+		rules = append(rules, IngressRule{
+			Host: host,
+			HTTP: HTTPIngressRuleValue{Paths: []HTTPIngressPath{{
+				Path:     path,
+				PathType: "Prefix",
+				Backend:  IngressBackend{Service: IngressServiceBackend{Name: name, Port: ServiceBackendPort{Name: portName}}},
+			}}},
+		})
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	return &Ingress{
+		TypeMeta: TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"},
+		Metadata: ObjectMeta{Name: name, Namespace: namespace, Labels: labels(app, discover.Web)},
+		Spec:     IngressSpec{IngressClassName: opts.IngressClassName, Rules: rules},
+	}
+}
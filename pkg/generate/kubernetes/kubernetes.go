@@ -0,0 +1,276 @@
+package kubernetes
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+)
+
+const (
+	// DefaultPort is the port Cloud Foundry assigns to the applications in the PORT environment variable, and where
+	// the routes send the traffic to.
+	DefaultPort = 8080
+	// servicePort is the port exposed by the generated Services.
+	servicePort = 80
+	// portName names the container port of the web processes.
+	portName = "http"
+
+	nameLabel      = "app.kubernetes.io/name"
+	componentLabel = "app.kubernetes.io/component"
+	// maxNameLength is the maximum length of the names of the generated resources, which must be valid DNS labels.
+	maxNameLength = 63
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Options configures the generation of the Kubernetes manifests.
+type Options struct {
+	// Namespace is the namespace of the generated resources. Defaults to the space of the application.
+	Namespace string
+	// ImageRegistry is the registry where the images of the applications that are built from buildpacks are pushed
+	// to. The image of those applications is `<registry>/<application name>:latest`.
+	ImageRegistry string
+	// IngressClassName is the class of the Ingresses generated for the HTTP routes. The default class of the cluster
+	// is used when empty.
+	IngressClassName string
+	// OpenShiftRoutes exposes the routes of the applications with OpenShift Routes instead of an Ingress.
+	OpenShiftRoutes bool
+	// Resources translates the memory and disk quotas of the processes into the requests and limits of their
+	// containers.
+	Resources ResourcePolicy
+	// CFEnv sets the variables that Cloud Foundry sets in the containers, like VCAP_SERVICES, VCAP_APPLICATION and
+	// CF_INSTANCE_IP, for the applications that read them.
+	CFEnv bool
+}
+
+// Result contains the Kubernetes resources generated for an application.
+type Result struct {
+	// Objects are the generated resources, in a stable order: a Deployment per process, followed by the Service of
+	// the web process and the Ingress or the OpenShift Routes of the HTTP routes when the application has routes, and
+	// a Secret and a ServiceBinding per service, followed by the Secret and the ConfigMap of the Cloud Foundry
+	// variables when they are set.
+	Objects []Object
+	// Warnings describe the parts of the application that need manual changes after the generation.
+	Warnings []string
+}
+
+// Generate converts the application into Kubernetes resources. Each process becomes a Deployment, whose pods run the
+// process and its sidecars with the resources of the resource policy, and whose probes are translated from the health
+// checks. The web process becomes a Service when the application has routes. The HTTP routes are exposed with an
+// Ingress or with OpenShift Routes, and the TCP routes are reported as warnings. The services are bound with
+// servicebinding.io ServiceBindings to Secrets that hold their credentials.
+func Generate(app discover.Application, opts Options) (*Result, error) {
+	if err := opts.Resources.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid resource policy: %w", err)
+	}
+	r := &Result{}
+	name := ResourceName(app.Metadata.Name)
+	namespace := opts.Namespace
+	if namespace == "" && app.Metadata.Space != "" {
+		namespace = ResourceName(app.Metadata.Space)
+	}
+	image := Image(app, opts.ImageRegistry)
+	if app.Docker.Image == "" {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("no container image is available for application %s: build %s from its source code", app.Metadata.Name, image))
+	} else if app.Docker.Username != "" {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("the image of application %s is pulled with the credentials of %s: add an image pull secret", app.Metadata.Name, app.Docker.Username))
+	}
+
+	exposed := []Object{}
+	for _, p := range Processes(app) {
+		d, warnings, err := deployment(app, p, namespace, image, opts.Resources)
+		if err != nil {
+			return nil, fmt.Errorf("process %s of application %s: %w", p.Type, app.Metadata.Name, err)
+		}
+		r.Warnings = append(r.Warnings, warnings...)
+		if opts.CFEnv {
+			for i := range d.Spec.Template.Spec.Containers {
+				c := &d.Spec.Template.Spec.Containers[i]
+				c.Env = append(c.Env, cfEnv(app, p)...)
+			}
+			if p.Instances > 1 {
+				r.Warnings = append(r.Warnings, fmt.Sprintf("process %s of application %s runs %d instances: CF_INSTANCE_INDEX is not set, since the pods of a Deployment have no index", p.Type, app.Metadata.Name, p.Instances))
+			}
+		}
+		c := &d.Spec.Template.Spec.Containers[0]
+		c.LivenessProbe, c.ReadinessProbe, c.StartupProbe, warnings = probes(app, p)
+		r.Warnings = append(r.Warnings, warnings...)
+		r.Objects = append(r.Objects, d)
+		if p.Type == discover.Web && HasRoutes(app) {
+			port := ServicePort{Name: portName, Protocol: "TCP", Port: servicePort, TargetPort: portName}
+			if opts.OpenShiftRoutes && usesHTTP2(app) {
+				port.AppProtocol = h2cAppProtocol
+			}
+			exposed = append(exposed, &Service{
+				TypeMeta: TypeMeta{APIVersion: "v1", Kind: "Service"},
+				Metadata: ObjectMeta{Name: name, Namespace: namespace, Labels: labels(app, p.Type)},
+				Spec:     ServiceSpec{Selector: labels(app, p.Type), Ports: []ServicePort{port}},
+			})
+			if opts.OpenShiftRoutes {
+				exposed = append(exposed, openShiftRoutes(app, namespace)...)
+			} else if i := ingress(app, namespace, opts); i != nil {
+				exposed = append(exposed, i)
+			}
+			r.Warnings = append(r.Warnings, tcpRouteWarnings(app)...)
+		}
+	}
+	r.Objects = append(r.Objects, exposed...)
+	bindings, warnings := serviceBindings(app, namespace)
+	r.Objects = append(r.Objects, bindings...)
+	r.Warnings = append(r.Warnings, warnings...)
+	if opts.CFEnv {
+		vcap, err := vcapObjects(app, namespace)
+		if err != nil {
+			return nil, err
+		}
+		r.Objects = append(r.Objects, vcap...)
+	}
+	return r, nil
+}
+
+// Processes returns the processes of the application. Applications without processes run a single web process,
+// like Cloud Foundry does.
+func Processes(app discover.Application) discover.Processes {
+	if len(app.Processes) > 0 {
+		return app.Processes
+	}
+	return discover.Processes{{
+		Type:      discover.Web,
+		Memory:    "1G",
+		Instances: max(app.Instances, 1),
+		HealthCheck: discover.ProbeSpec{
+			Type:     discover.PortProbeType,
+			Endpoint: "/",
+			Timeout:  1,
+			Interval: 30,
+		},
+		ReadinessCheck: discover.ProbeSpec{
+			Type:     discover.ProcessProbeType,
+			Endpoint: "/",
+			Timeout:  1,
+			Interval: 30,
+		},
+		LogRateLimit: "16K",
+	}}
+}
+
+// HasRoutes returns true when Cloud Foundry maps the application to at least one route.
+func HasRoutes(app discover.Application) bool {
+	return !app.Routes.NoRoute && (len(app.Routes.Routes) > 0 || app.Routes.RandomRoute)
+}
+
+// SplitRoute splits a Cloud Foundry route into its host name, port and path. The port is zero for the HTTP
+// routes, and the path is empty for the routes without it.
+func SplitRoute(route string) (host string, port int, path string) {
+	host = route
+	if i := strings.Index(host, "/"); i >= 0 {
+		host, path = host[:i], host[i:]
+	}
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		if p, err := strconv.Atoi(host[i+1:]); err == nil {
+			host, port = host[:i], p
+		}
+	}
+	return host, port, strings.TrimSuffix(path, "/")
+}
+
+// Image returns the image of the application: the Docker image it is deployed from, or the image to build from its
+// source code when it is deployed with buildpacks.
+func Image(app discover.Application, registry string) string {
+	if app.Docker.Image != "" {
+		return app.Docker.Image
+	}
+	image := ResourceName(app.Metadata.Name) + ":latest"
+	if registry != "" {
+		image = strings.TrimSuffix(registry, "/") + "/" + image
+	}
+	return image
+}
+
+// ResourceName converts name into a valid DNS label, which is the format required for the names of most of the
+// Kubernetes resources.
+func ResourceName(name string) string {
+	n := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(n) > maxNameLength {
+		n = strings.TrimRight(n[:maxNameLength], "-")
+	}
+	if n == "" {
+		return "application"
+	}
+	return n
+}
+
+// WorkloadName returns the name of the resources generated for the process. The web process is named after the
+// application, and the other processes get the process type as suffix.
+func WorkloadName(app discover.Application, processType discover.ProcessType) string {
+	if processType == discover.Web {
+		return ResourceName(app.Metadata.Name)
+	}
+	return ResourceName(app.Metadata.Name + "-" + string(processType))
+}
+
+func labels(app discover.Application, processType discover.ProcessType) map[string]string {
+	return map[string]string{
+		nameLabel:      ResourceName(app.Metadata.Name),
+		componentLabel: ResourceName(string(processType)),
+	}
+}
+
+// deployment returns the Deployment of the process, whose pods run the process and its sidecars.
+func deployment(app discover.Application, p discover.ProcessSpec, namespace, image string, policy ResourcePolicy) (*Deployment, []string, error) {
+	sidecars, warnings, err := sidecarContainers(app, p, image, policy)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Cloud Foundry counts the memory of the sidecars against the memory of the process they run with.
+	if p.Memory, err = app.EffectiveMemory(p); err != nil {
+		return nil, nil, err
+	}
+	resources, err := policy.Resources(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := Container{
+		Name:      ResourceName(string(p.Type)),
+		Image:     image,
+		Env:       env(app, p.Type),
+		Resources: resources,
+	}
+	if p.Command != "" {
+		// Cloud Foundry runs the commands with a shell.
+		c.Command = []string{"/bin/sh", "-c", p.Command}
+	}
+	if p.Type == discover.Web {
+		c.Ports = []ContainerPort{{Name: portName, ContainerPort: DefaultPort, Protocol: "TCP"}}
+	}
+	return &Deployment{
+		TypeMeta: TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		Metadata: ObjectMeta{Name: WorkloadName(app, p.Type), Namespace: namespace, Labels: labels(app, p.Type)},
+		Spec: DeploymentSpec{
+			Replicas: p.Instances,
+			Selector: LabelSelector{MatchLabels: labels(app, p.Type)},
+			Template: PodTemplateSpec{
+				Metadata: ObjectMeta{Labels: labels(app, p.Type)},
+				Spec:     PodSpec{Containers: append([]Container{c}, sidecars...)},
+			},
+		},
+	}, warnings, nil
+}
+
+// env returns the environment variables of the application sorted by name. The web process also gets the PORT
+// variable set by Cloud Foundry, unless the application overrides it.
+func env(app discover.Application, processType discover.ProcessType) []EnvVar {
+	vars := []EnvVar{}
+	for name, value := range app.Env {
+		vars = append(vars, EnvVar{Name: name, Value: value})
+	}
+	if _, ok := app.Env["PORT"]; !ok && processType == discover.Web {
+		vars = append(vars, EnvVar{Name: "PORT", Value: strconv.Itoa(DefaultPort)})
+	}
+	slices.SortFunc(vars, func(a, b EnvVar) int { return strings.Compare(a.Name, b.Name) })
+	return vars
+}
@@ -0,0 +1,243 @@
+package kubernetes
+
+import (
+	"bytes"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	"github.com/gciavarrini/cf-application-discovery/pkg/output"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// render returns the objects as YAML documents.
+func render(objects []Object) string {
+	b := bytes.Buffer{}
+	Expect(output.Encode(&b, output.YAMLFormat, objects)).To(Succeed())
+	return b.String()
+}
+
+// sampleApplication returns an application with a web and a worker process and a route.
+func sampleApplication() discover.Application {
+	return discover.Application{
+		Metadata:  discover.Metadata{Name: "My_App", Space: "dev", Version: "1"},
+		Env:       map[string]string{"LOG_LEVEL": "debug", "DB_HOST": "db.example.com"},
+		Routes:    discover.RouteSpec{Routes: discover.Routes{{Route: "my-app.example.com"}}},
+		Timeout:   60,
+		Instances: 1,
+		Docker:    discover.Docker{Image: "registry.example.com/my-app:1.0"},
+		Processes: discover.Processes{
+			{Type: discover.Web, Command: "./bin/web --port $PORT", Memory: "512M", Instances: 3, LogRateLimit: "16K"},
+			{Type: discover.Worker, Command: "./bin/worker", Memory: "1G", Instances: 1, LogRateLimit: "16K"},
+		},
+	}
+}
+
+var _ = Describe("Kubernetes generator", func() {
+	It("generates a Deployment per process and a Service and an Ingress for the routed web process", func() {
+		r, err := Generate(sampleApplication(), Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Warnings).To(BeEmpty())
+		Expect(render(r.Objects)).To(Equal(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-app
+  namespace: dev
+  labels:
+    app.kubernetes.io/component: web
+    app.kubernetes.io/name: my-app
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app.kubernetes.io/component: web
+      app.kubernetes.io/name: my-app
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/component: web
+        app.kubernetes.io/name: my-app
+    spec:
+      containers:
+        - name: web
+          image: registry.example.com/my-app:1.0
+          command:
+            - /bin/sh
+            - -c
+            - ./bin/web --port $PORT
+          ports:
+            - name: http
+              containerPort: 8080
+              protocol: TCP
+          env:
+            - name: DB_HOST
+              value: db.example.com
+            - name: LOG_LEVEL
+              value: debug
+            - name: PORT
+              value: "8080"
+          resources:
+            limits:
+              ephemeral-storage: 1Gi
+              memory: 512Mi
+            requests:
+              cpu: 63m
+              ephemeral-storage: 1Gi
+              memory: 512Mi
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-app-worker
+  namespace: dev
+  labels:
+    app.kubernetes.io/component: worker
+    app.kubernetes.io/name: my-app
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app.kubernetes.io/component: worker
+      app.kubernetes.io/name: my-app
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/component: worker
+        app.kubernetes.io/name: my-app
+    spec:
+      containers:
+        - name: worker
+          image: registry.example.com/my-app:1.0
+          command:
+            - /bin/sh
+            - -c
+            - ./bin/worker
+          env:
+            - name: DB_HOST
+              value: db.example.com
+            - name: LOG_LEVEL
+              value: debug
+          resources:
+            limits:
+              ephemeral-storage: 1Gi
+              memory: 1Gi
+            requests:
+              cpu: 125m
+              ephemeral-storage: 1Gi
+              memory: 1Gi
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: my-app
+  namespace: dev
+  labels:
+    app.kubernetes.io/component: web
+    app.kubernetes.io/name: my-app
+spec:
+  selector:
+    app.kubernetes.io/component: web
+    app.kubernetes.io/name: my-app
+  ports:
+    - name: http
+      protocol: TCP
+      port: 80
+      targetPort: http
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: my-app
+  namespace: dev
+  labels:
+    app.kubernetes.io/component: web
+    app.kubernetes.io/name: my-app
+spec:
+  rules:
+    - host: my-app.example.com
+      http:
+        paths:
+          - path: /
+            pathType: Prefix
+            backend:
+              service:
+                name: my-app
+                port:
+                  name: http
+`))
+	})
+
+	It("renders the same output on every run", func() {
+		first, err := Generate(sampleApplication(), Options{})
+		Expect(err).NotTo(HaveOccurred())
+		for i := 0; i < 10; i++ {
+			r, err := Generate(sampleApplication(), Options{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(render(r.Objects)).To(Equal(render(first.Objects)))
+		}
+	})
+
+	It("runs a single web process when the application has no processes", func() {
+		app := discover.Application{Metadata: discover.Metadata{Name: "foo"}, Instances: 2, Routes: discover.RouteSpec{NoRoute: true}}
+		r, err := Generate(app, Options{Namespace: "team-a", ImageRegistry: "quay.io/team-a/"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Objects).To(HaveLen(1))
+		d := r.Objects[0].(*Deployment)
+		Expect(d.Metadata.Namespace).To(Equal("team-a"))
+		Expect(d.Spec.Replicas).To(Equal(2))
+		Expect(d.Spec.Template.Spec.Containers[0].Image).To(Equal("quay.io/team-a/foo:latest"))
+		Expect(d.Spec.Template.Spec.Containers[0].Resources.Limits).To(HaveKeyWithValue("memory", "1Gi"))
+		Expect(r.Warnings).To(Equal([]string{"no container image is available for application foo: build quay.io/team-a/foo:latest from its source code"}))
+	})
+
+	It("exposes the HTTP routes with an Ingress and skips the TCP routes", func() {
+		app := sampleApplication()
+		app.Routes.Routes = discover.Routes{
+			{Route: "my-app.example.com/api"},
+			{Route: "tcp.example.com:1034", Protocol: discover.TCPRouteProtocol},
+		}
+		r, err := Generate(app, Options{IngressClassName: "nginx"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Objects).To(HaveLen(4))
+		i := r.Objects[3].(*Ingress)
+		Expect(i.Spec.IngressClassName).To(Equal("nginx"))
+		Expect(i.Spec.Rules).To(HaveLen(1))
+		Expect(i.Spec.Rules[0].Host).To(Equal("my-app.example.com"))
+		Expect(i.Spec.Rules[0].HTTP.Paths[0].Path).To(Equal("/api"))
+
+		app.Routes.Routes = app.Routes.Routes[1:]
+		r, err = Generate(app, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Objects).To(HaveLen(3))
+		Expect(r.Objects[2]).To(BeAssignableToTypeOf(&Service{}))
+	})
+
+	It("fails on invalid amounts of memory", func() {
+		app := sampleApplication()
+		app.Processes[1].Memory = "lots"
+		_, err := Generate(app, Options{})
+		Expect(err).To(MatchError(`process worker of application My_App: memory: invalid quantity "lots": must be a number followed by a unit such as M or G`))
+	})
+
+	DescribeTable("converts names into DNS labels", func(name, expected string) {
+		Expect(ResourceName(name)).To(Equal(expected))
+	},
+		Entry("with a valid name", "my-app", "my-app"),
+		Entry("with upper case letters and symbols", "My_App.v2", "my-app-v2"),
+		Entry("with leading and trailing symbols", "--app--", "app"),
+		Entry("with no valid characters", "___", "application"),
+		Entry("with a long name", "a123456789-123456789-123456789-123456789-123456789-123456789-12-4", "a123456789-123456789-123456789-123456789-123456789-123456789-12"),
+	)
+})
+
+var _ = DescribeTable("Split routes", func(route, host string, port int, path string) {
+	h, p, pa := SplitRoute(route)
+	Expect(h).To(Equal(host))
+	Expect(p).To(Equal(port))
+	Expect(pa).To(Equal(path))
+},
+	Entry("with a host name", "my-app.example.com", "my-app.example.com", 0, ""),
+	Entry("with a path", "my-app.example.com/api/v1", "my-app.example.com", 0, "/api/v1"),
+	Entry("with a trailing slash", "my-app.example.com/", "my-app.example.com", 0, ""),
+	Entry("with a port", "tcp.example.com:1034", "tcp.example.com", 1034, ""),
+	Entry("with a wildcard host", "*.example.com/static", "*.example.com", 0, "/static"),
+)
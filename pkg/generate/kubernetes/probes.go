@@ -0,0 +1,64 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+)
+
+// startupPeriodSeconds is the interval between the checks while the application starts, during which Cloud Foundry
+// runs the health check continuously until it succeeds or the start timeout expires.
+const startupPeriodSeconds = 2
+
+// probes translates the health and readiness checks of the process into the probes of its container. The startup
+// probe gives the application the time allowed by the Cloud Foundry start timeout to pass the health check before the
+// liveness probe takes over. The port and http checks of the processes that don't listen on a port are handled like
+// process checks, and the checks without an equivalent are reported as warnings.
+func probes(app discover.Application, p discover.ProcessSpec) (liveness, readiness, startup *Probe, warnings []string) {
+	if !listens(app, p) {
+		if p.HealthCheck.Type == discover.PortProbeType || p.HealthCheck.Type == discover.HTTPProbeType {
+			warnings = append(warnings, fmt.Sprintf("process %s of application %s has a %s health check but doesn't listen on a port, since only the web process gets the PORT variable: it is handled like a process health check", p.Type, app.Metadata.Name, p.HealthCheck.Type))
+			p.HealthCheck.Type = discover.ProcessProbeType
+		}
+		p.ReadinessCheck.Type = discover.ProcessProbeType
+	}
+	if p.HealthCheck.Type == discover.ProcessProbeType {
+		warnings = append(warnings, fmt.Sprintf("process %s of application %s uses a process health check, which has no probe equivalent: Kubernetes only restarts the container when its main process exits", p.Type, app.Metadata.Name))
+	}
+	liveness = probe(p.HealthCheck)
+	if liveness != nil {
+		// Cloud Foundry restarts the instances on the first failed health check.
+		liveness.FailureThreshold = 1
+		startup = &Probe{
+			ProbeHandler:     liveness.ProbeHandler,
+			PeriodSeconds:    startupPeriodSeconds,
+			TimeoutSeconds:   liveness.TimeoutSeconds,
+			FailureThreshold: max((app.Timeout+startupPeriodSeconds-1)/startupPeriodSeconds, 1),
+		}
+	}
+	// A process readiness check, the default, considers the instances ready while they run, like Kubernetes does
+	// for the containers without readiness probe.
+	readiness = probe(p.ReadinessCheck)
+	return liveness, readiness, startup, warnings
+}
+
+// listens reports whether the process listens on the port of the probes: the web process gets the PORT variable, and
+// the other processes only when the application sets it.
+func listens(app discover.Application, p discover.ProcessSpec) bool {
+	_, ok := app.Env["PORT"]
+	return p.Type == discover.Web || ok
+}
+
+// probe returns the probe that runs the check, or nil for checks that only verify that the process is running.
+func probe(check discover.ProbeSpec) *Probe {
+	p := &Probe{PeriodSeconds: check.Interval, TimeoutSeconds: check.Timeout}
+	switch check.Type {
+	case discover.HTTPProbeType:
+		p.HTTPGet = &HTTPGetAction{Path: check.Endpoint, Port: DefaultPort}
+	case discover.PortProbeType:
+		p.TCPSocket = &TCPSocketAction{Port: DefaultPort}
+	default:
+		return nil
+	}
+	return p
+}
@@ -0,0 +1,114 @@
+package kubernetes
+
+import (
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Probes", func() {
+	check := func(t discover.ProbeType) discover.ProbeSpec {
+		return discover.ProbeSpec{Type: t, Endpoint: "/health", Timeout: 5, Interval: 10}
+	}
+
+	DescribeTable("translates the health checks", func(timeout int, health, readiness discover.ProbeSpec, expectedLiveness, expectedReadiness, expectedStartup *Probe, expectedWarnings int) {
+		app := discover.Application{Metadata: discover.Metadata{Name: "foo"}, Timeout: timeout}
+		l, r, s, w := probes(app, discover.ProcessSpec{Type: discover.Web, HealthCheck: health, ReadinessCheck: readiness})
+		Expect(l).To(Equal(expectedLiveness))
+		Expect(r).To(Equal(expectedReadiness))
+		Expect(s).To(Equal(expectedStartup))
+		Expect(w).To(HaveLen(expectedWarnings))
+	},
+		Entry("http health check", 60, check(discover.HTTPProbeType), check(discover.ProcessProbeType),
+			&Probe{ProbeHandler: ProbeHandler{HTTPGet: &HTTPGetAction{Path: "/health", Port: 8080}}, PeriodSeconds: 10, TimeoutSeconds: 5, FailureThreshold: 1},
+			nil,
+			&Probe{ProbeHandler: ProbeHandler{HTTPGet: &HTTPGetAction{Path: "/health", Port: 8080}}, PeriodSeconds: 2, TimeoutSeconds: 5, FailureThreshold: 30},
+			0),
+		Entry("port health check with an odd start timeout", 45, check(discover.PortProbeType), check(discover.HTTPProbeType),
+			&Probe{ProbeHandler: ProbeHandler{TCPSocket: &TCPSocketAction{Port: 8080}}, PeriodSeconds: 10, TimeoutSeconds: 5, FailureThreshold: 1},
+			&Probe{ProbeHandler: ProbeHandler{HTTPGet: &HTTPGetAction{Path: "/health", Port: 8080}}, PeriodSeconds: 10, TimeoutSeconds: 5},
+			&Probe{ProbeHandler: ProbeHandler{TCPSocket: &TCPSocketAction{Port: 8080}}, PeriodSeconds: 2, TimeoutSeconds: 5, FailureThreshold: 23},
+			0),
+		Entry("process health check", 60, check(discover.ProcessProbeType), check(discover.PortProbeType),
+			nil,
+			&Probe{ProbeHandler: ProbeHandler{TCPSocket: &TCPSocketAction{Port: 8080}}, PeriodSeconds: 10, TimeoutSeconds: 5},
+			nil,
+			1),
+	)
+
+	It("flags the process health checks in the generated warnings", func() {
+		app := sampleApplication()
+		app.Processes[1].HealthCheck = check(discover.ProcessProbeType)
+		r, err := Generate(app, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Warnings).To(Equal([]string{"process worker of application My_App uses a process health check, which has no probe equivalent: Kubernetes only restarts the container when its main process exits"}))
+	})
+
+	DescribeTable("only probes the processes that listen on a port", func(env map[string]string, health discover.ProbeType, expectedLiveness *Probe, expectedWarnings []string) {
+		app := discover.Application{Metadata: discover.Metadata{Name: "foo"}, Env: env, Timeout: 60}
+		l, r, s, w := probes(app, discover.ProcessSpec{Type: discover.Worker, HealthCheck: check(health), ReadinessCheck: check(discover.PortProbeType)})
+		Expect(l).To(Equal(expectedLiveness))
+		Expect(r).To(BeNil())
+		Expect(s).To(BeNil())
+		Expect(w).To(Equal(expectedWarnings))
+	},
+		Entry("worker with a port health check", nil, discover.PortProbeType, nil, []string{
+			"process worker of application foo has a port health check but doesn't listen on a port, since only the web process gets the PORT variable: it is handled like a process health check",
+			"process worker of application foo uses a process health check, which has no probe equivalent: Kubernetes only restarts the container when its main process exits",
+		}),
+		Entry("worker with an http health check", nil, discover.HTTPProbeType, nil, []string{
+			"process worker of application foo has a http health check but doesn't listen on a port, since only the web process gets the PORT variable: it is handled like a process health check",
+			"process worker of application foo uses a process health check, which has no probe equivalent: Kubernetes only restarts the container when its main process exits",
+		}),
+	)
+
+	It("probes the workers of the applications that set PORT", func() {
+		app := discover.Application{Metadata: discover.Metadata{Name: "foo"}, Env: map[string]string{"PORT": "8080"}, Timeout: 60}
+		l, r, _, w := probes(app, discover.ProcessSpec{Type: discover.Worker, HealthCheck: check(discover.PortProbeType), ReadinessCheck: check(discover.ProcessProbeType)})
+		Expect(l).To(Equal(&Probe{ProbeHandler: ProbeHandler{TCPSocket: &TCPSocketAction{Port: 8080}}, PeriodSeconds: 10, TimeoutSeconds: 5, FailureThreshold: 1}))
+		Expect(r).To(BeNil())
+		Expect(w).To(BeEmpty())
+	})
+
+	It("doesn't probe the workers discovered with the default health check", func() {
+		app, err := discover.Discover(discover.AppManifest{Name: "foo", Processes: &discover.AppManifestProcesses{
+			{Type: discover.WebAppProcessType},
+			{Type: discover.WorkerAppProcessType, Command: "./bin/worker"},
+		}}, "", "dev")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(app.Processes[1].HealthCheck.Type).To(Equal(discover.PortProbeType))
+		r, err := Generate(app, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		web := r.Objects[0].(*Deployment).Spec.Template.Spec.Containers[0]
+		Expect(web.LivenessProbe.TCPSocket).To(Equal(&TCPSocketAction{Port: 8080}))
+		Expect(web.StartupProbe).NotTo(BeNil())
+		worker := r.Objects[1].(*Deployment).Spec.Template.Spec.Containers[0]
+		Expect(worker.LivenessProbe).To(BeNil())
+		Expect(worker.ReadinessProbe).To(BeNil())
+		Expect(worker.StartupProbe).To(BeNil())
+		Expect(r.Warnings).To(ContainElement("process worker of application foo has a port health check but doesn't listen on a port, since only the web process gets the PORT variable: it is handled like a process health check"))
+	})
+
+	It("renders the probes of the container", func() {
+		app := sampleApplication()
+		app.Processes[0].HealthCheck = check(discover.HTTPProbeType)
+		r, err := Generate(app, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(render(r.Objects[:1])).To(ContainSubstring(`
+          livenessProbe:
+            httpGet:
+              path: /health
+              port: 8080
+            periodSeconds: 10
+            timeoutSeconds: 5
+            failureThreshold: 1
+          startupProbe:
+            httpGet:
+              path: /health
+              port: 8080
+            periodSeconds: 2
+            timeoutSeconds: 5
+            failureThreshold: 30
+`))
+	})
+})
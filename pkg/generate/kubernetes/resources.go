@@ -0,0 +1,224 @@
+package kubernetes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+)
+
+const (
+	// DefaultCPUPerGigabyte is the CPU requested per gigabyte of memory by the default policy, which matches the CPU
+	// share of an application on a Cloud Foundry cell with 4 CPUs and 32GB of memory.
+	DefaultCPUPerGigabyte = "125m"
+	// DefaultMinCPU is the minimum CPU requested by the default policy, like the minimum CPU share in Cloud Foundry.
+	DefaultMinCPU = "10m"
+	// defaultDiskQuota is the disk quota of the processes that don't set it in Cloud Foundry.
+	defaultDiskQuota discover.Quantity = "1G"
+
+	cpuResource              = "cpu"
+	memoryResource           = "memory"
+	ephemeralStorageResource = "ephemeral-storage"
+)
+
+// ResourcePolicy translates the quotas of the Cloud Foundry processes into the resources of their containers. In
+// Cloud Foundry, the memory of a process is a hard limit, the CPU is shared among the applications of a cell in
+// proportion to their memory, and the disk quota bounds the disk of each instance.
+//
+// The zero value of each field selects its default, so the zero policy requests and limits the memory of the
+// process, requests DefaultCPUPerGigabyte of CPU per gigabyte of memory, and requests and limits the disk quota of
+// the process as ephemeral storage.
+type ResourcePolicy struct {
+	// MemoryRequestRatio is the fraction of the memory limit requested by the container, between 0 and 1. Defaults
+	// to 1, which guarantees the memory like Cloud Foundry does.
+	MemoryRequestRatio float64 `yaml:"memoryRequestRatio,omitempty"`
+	// MinMemory and MaxMemory clamp the memory limit of the containers.
+	MinMemory discover.Quantity `yaml:"minMemory,omitempty"`
+	MaxMemory discover.Quantity `yaml:"maxMemory,omitempty"`
+	// CPUPerGigabyte is the CPU requested per gigabyte of memory limit, as a Kubernetes quantity like `125m` or
+	// `0.5`. Defaults to DefaultCPUPerGigabyte.
+	CPUPerGigabyte string `yaml:"cpuPerGigabyte,omitempty"`
+	// CPULimitRatio is the CPU limit of the containers as a multiple of their CPU request, at least 1. The CPU is
+	// not limited when it is not set, so that the applications can use the idle CPU like they do in Cloud Foundry.
+	CPULimitRatio float64 `yaml:"cpuLimitRatio,omitempty"`
+	// MinCPU and MaxCPU clamp the CPU request of the containers. MinCPU defaults to DefaultMinCPU.
+	MinCPU string `yaml:"minCPU,omitempty"`
+	MaxCPU string `yaml:"maxCPU,omitempty"`
+	// EphemeralStorageRequestRatio is the fraction of the disk quota requested as ephemeral storage, between 0 and
+	// 1. Defaults to 1.
+	EphemeralStorageRequestRatio float64 `yaml:"ephemeralStorageRequestRatio,omitempty"`
+}
+
+// Validate checks that the ratios are in range and that the clamps are valid quantities.
+func (p ResourcePolicy) Validate() error {
+	if p.MemoryRequestRatio < 0 || p.MemoryRequestRatio > 1 {
+		return fmt.Errorf("invalid memory request ratio %v: must be between 0 and 1", p.MemoryRequestRatio)
+	}
+	if p.EphemeralStorageRequestRatio < 0 || p.EphemeralStorageRequestRatio > 1 {
+		return fmt.Errorf("invalid ephemeral storage request ratio %v: must be between 0 and 1", p.EphemeralStorageRequestRatio)
+	}
+	if p.CPULimitRatio != 0 && p.CPULimitRatio < 1 {
+		return fmt.Errorf("invalid CPU limit ratio %v: must be at least 1", p.CPULimitRatio)
+	}
+	minMemory, maxMemory, err := p.memoryBounds()
+	if err != nil {
+		return err
+	}
+	if maxMemory > 0 && minMemory > maxMemory {
+		return fmt.Errorf("the minimum memory %s is greater than the maximum memory %s", p.MinMemory, p.MaxMemory)
+	}
+	if _, err := parseCPU(p.CPUPerGigabyte); err != nil {
+		return err
+	}
+	minCPU, err := parseCPU(p.MinCPU)
+	if err != nil {
+		return err
+	}
+	maxCPU, err := parseCPU(p.MaxCPU)
+	if err != nil {
+		return err
+	}
+	if maxCPU > 0 && minCPU > maxCPU {
+		return fmt.Errorf("the minimum CPU %s is greater than the maximum CPU %s", p.MinCPU, p.MaxCPU)
+	}
+	return nil
+}
+
+// Resources returns the resources of the container of the process. The memory limit is the memory of the process
+// within the clamps, and the CPU request is proportional to it.
+func (p ResourcePolicy) Resources(process discover.ProcessSpec) (ResourceRequirements, error) {
+	if err := p.Validate(); err != nil {
+		return ResourceRequirements{}, err
+	}
+	memory, err := limitedBytes(process.Memory)
+	if err != nil {
+		return ResourceRequirements{}, fmt.Errorf("memory: %w", err)
+	}
+	minMemory, maxMemory, _ := p.memoryBounds()
+	memory = max(memory, minMemory)
+	if maxMemory > 0 {
+		memory = min(memory, maxMemory)
+	}
+	disk := process.DiskQuota
+	if disk == "" {
+		disk = defaultDiskQuota
+	}
+	storage, err := limitedBytes(disk)
+	if err != nil {
+		return ResourceRequirements{}, fmt.Errorf("disk quota: %w", err)
+	}
+
+	cpuPerGigabyte, _ := parseCPU(defaultString(p.CPUPerGigabyte, DefaultCPUPerGigabyte))
+	minCPU, _ := parseCPU(defaultString(p.MinCPU, DefaultMinCPU))
+	maxCPU, _ := parseCPU(p.MaxCPU)
+	cpu := max(int64(math.Ceil(float64(cpuPerGigabyte)*float64(memory)/float64(discover.Gigabyte))), minCPU)
+	if maxCPU > 0 {
+		cpu = min(cpu, maxCPU)
+	}
+
+	r := ResourceRequirements{
+		Limits: map[string]string{
+			memoryResource:           bytesQuantity(memory),
+			ephemeralStorageResource: bytesQuantity(storage),
+		},
+		Requests: map[string]string{
+			cpuResource:              cpuQuantity(cpu),
+			memoryResource:           bytesQuantity(ratio(memory, p.MemoryRequestRatio)),
+			ephemeralStorageResource: bytesQuantity(ratio(storage, p.EphemeralStorageRequestRatio)),
+		},
+	}
+	if p.CPULimitRatio > 0 {
+		r.Limits[cpuResource] = cpuQuantity(int64(math.Ceil(float64(cpu) * p.CPULimitRatio)))
+	}
+	return r, nil
+}
+
+// sidecarResources returns the resources of the container of a sidecar with the given memory limit, or without
+// memory limit when it is zero. The sidecars have no ephemeral storage of their own, since the disk quota of the
+// process is given to the container of the process.
+func (p ResourcePolicy) sidecarResources(memory int64) ResourceRequirements {
+	r, _ := p.Resources(discover.ProcessSpec{Memory: discover.QuantityFromBytes(memory)})
+	delete(r.Limits, ephemeralStorageResource)
+	delete(r.Requests, ephemeralStorageResource)
+	if memory == 0 {
+		delete(r.Limits, memoryResource)
+		delete(r.Requests, memoryResource)
+	}
+	return r
+}
+
+// memoryBounds returns the clamps of the memory limit in bytes, which are zero when not set.
+func (p ResourcePolicy) memoryBounds() (minMemory, maxMemory int64, err error) {
+	if p.MinMemory != "" {
+		if minMemory, err = limitedBytes(p.MinMemory); err != nil {
+			return 0, 0, fmt.Errorf("minimum memory: %w", err)
+		}
+	}
+	if p.MaxMemory != "" {
+		if maxMemory, err = limitedBytes(p.MaxMemory); err != nil {
+			return 0, 0, fmt.Errorf("maximum memory: %w", err)
+		}
+	}
+	return minMemory, maxMemory, nil
+}
+
+// limitedBytes returns the bytes of q, which can't be unlimited.
+func limitedBytes(q discover.Quantity) (int64, error) {
+	bytes, err := q.Bytes()
+	if err == nil && bytes < 0 {
+		err = fmt.Errorf("invalid quantity %q: must be limited", q)
+	}
+	return bytes, err
+}
+
+// ratio returns the fraction of bytes rounded up to a whole mebibyte, or bytes when the ratio is not set. The
+// result never exceeds bytes, since the requests can't be greater than the limits.
+func ratio(bytes int64, r float64) int64 {
+	if r == 0 || r == 1 {
+		return bytes
+	}
+	mebibytes := math.Ceil(float64(bytes) * r / float64(discover.Megabyte))
+	return min(int64(mebibytes)*discover.Megabyte, bytes)
+}
+
+// bytesQuantity formats the amount of bytes as a Kubernetes quantity with a binary suffix.
+func bytesQuantity(bytes int64) string {
+	q, _ := discover.QuantityFromBytes(bytes).KubernetesQuantity()
+	return q
+}
+
+// parseCPU parses a Kubernetes CPU quantity, like `100m` or `0.5`, into millicores. An empty string is zero.
+func parseCPU(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if m, ok := strings.CutSuffix(s, "m"); ok {
+		n, err := strconv.ParseInt(m, 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid CPU quantity %q", s)
+		}
+		return n, nil
+	}
+	cores, err := strconv.ParseFloat(s, 64)
+	if err != nil || cores < 0 {
+		return 0, fmt.Errorf("invalid CPU quantity %q", s)
+	}
+	return int64(math.Ceil(cores * 1000)), nil
+}
+
+// cpuQuantity formats the millicores as a Kubernetes CPU quantity.
+func cpuQuantity(millicores int64) string {
+	if millicores%1000 == 0 {
+		return strconv.FormatInt(millicores/1000, 10)
+	}
+	return strconv.FormatInt(millicores, 10) + "m"
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
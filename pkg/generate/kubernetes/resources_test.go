@@ -0,0 +1,82 @@
+package kubernetes
+
+import (
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resource policy", func() {
+	DescribeTable("translates the quotas of the process into requests and limits", func(policy ResourcePolicy, process discover.ProcessSpec, expected ResourceRequirements) {
+		Expect(policy.Resources(process)).To(Equal(expected))
+	},
+		Entry("with the default policy",
+			ResourcePolicy{},
+			discover.ProcessSpec{Memory: "2G", DiskQuota: "4G"},
+			ResourceRequirements{
+				Limits:   map[string]string{"memory": "2Gi", "ephemeral-storage": "4Gi"},
+				Requests: map[string]string{"cpu": "250m", "memory": "2Gi", "ephemeral-storage": "4Gi"},
+			}),
+		Entry("with the default disk quota of Cloud Foundry",
+			ResourcePolicy{},
+			discover.ProcessSpec{Memory: "64M"},
+			ResourceRequirements{
+				Limits:   map[string]string{"memory": "64Mi", "ephemeral-storage": "1Gi"},
+				Requests: map[string]string{"cpu": "10m", "memory": "64Mi", "ephemeral-storage": "1Gi"},
+			}),
+		Entry("with request ratios",
+			ResourcePolicy{MemoryRequestRatio: 0.5, EphemeralStorageRequestRatio: 0.25},
+			discover.ProcessSpec{Memory: "1G", DiskQuota: "2G"},
+			ResourceRequirements{
+				Limits:   map[string]string{"memory": "1Gi", "ephemeral-storage": "2Gi"},
+				Requests: map[string]string{"cpu": "125m", "memory": "512Mi", "ephemeral-storage": "512Mi"},
+			}),
+		Entry("with request ratios and quotas that are not whole mebibytes",
+			ResourcePolicy{MemoryRequestRatio: 0.99, EphemeralStorageRequestRatio: 0.5},
+			discover.ProcessSpec{Memory: "1000K", DiskQuota: "1500B"},
+			ResourceRequirements{
+				Limits:   map[string]string{"memory": "1000Ki", "ephemeral-storage": "1500"},
+				Requests: map[string]string{"cpu": "10m", "memory": "1000Ki", "ephemeral-storage": "1500"},
+			}),
+		Entry("with a CPU limit and a custom share",
+			ResourcePolicy{CPUPerGigabyte: "0.5", CPULimitRatio: 4},
+			discover.ProcessSpec{Memory: "1G"},
+			ResourceRequirements{
+				Limits:   map[string]string{"cpu": "2", "memory": "1Gi", "ephemeral-storage": "1Gi"},
+				Requests: map[string]string{"cpu": "500m", "memory": "1Gi", "ephemeral-storage": "1Gi"},
+			}),
+		Entry("with the memory and CPU clamps",
+			ResourcePolicy{MaxMemory: "4G", MinCPU: "100m", MaxCPU: "300m"},
+			discover.ProcessSpec{Memory: "8G"},
+			ResourceRequirements{
+				Limits:   map[string]string{"memory": "4Gi", "ephemeral-storage": "1Gi"},
+				Requests: map[string]string{"cpu": "300m", "memory": "4Gi", "ephemeral-storage": "1Gi"},
+			}),
+		Entry("with a minimum memory",
+			ResourcePolicy{MinMemory: "256M", MinCPU: "100m"},
+			discover.ProcessSpec{Memory: "128M"},
+			ResourceRequirements{
+				Limits:   map[string]string{"memory": "256Mi", "ephemeral-storage": "1Gi"},
+				Requests: map[string]string{"cpu": "100m", "memory": "256Mi", "ephemeral-storage": "1Gi"},
+			}),
+	)
+
+	DescribeTable("rejects invalid policies", func(policy ResourcePolicy, message string) {
+		Expect(policy.Validate()).To(MatchError(message))
+		_, err := Generate(sampleApplication(), Options{Resources: policy})
+		Expect(err).To(MatchError("invalid resource policy: " + message))
+	},
+		Entry("with a memory request ratio over 1", ResourcePolicy{MemoryRequestRatio: 1.5}, "invalid memory request ratio 1.5: must be between 0 and 1"),
+		Entry("with a negative ephemeral storage ratio", ResourcePolicy{EphemeralStorageRequestRatio: -1}, "invalid ephemeral storage request ratio -1: must be between 0 and 1"),
+		Entry("with a CPU limit under the request", ResourcePolicy{CPULimitRatio: 0.5}, "invalid CPU limit ratio 0.5: must be at least 1"),
+		Entry("with an invalid memory clamp", ResourcePolicy{MaxMemory: "4Gi"}, `maximum memory: invalid quantity "4Gi": unknown unit "Gi", must be one of B, K, KB, M, MB, G, GB, T or TB`),
+		Entry("with crossed memory clamps", ResourcePolicy{MinMemory: "2G", MaxMemory: "1G"}, "the minimum memory 2G is greater than the maximum memory 1G"),
+		Entry("with an invalid CPU quantity", ResourcePolicy{CPUPerGigabyte: "fast"}, `invalid CPU quantity "fast"`),
+		Entry("with crossed CPU clamps", ResourcePolicy{MinCPU: "1", MaxCPU: "500m"}, "the minimum CPU 1 is greater than the maximum CPU 500m"),
+	)
+
+	It("rejects unlimited amounts of memory", func() {
+		_, err := ResourcePolicy{}.Resources(discover.ProcessSpec{Memory: "-1"})
+		Expect(err).To(MatchError(`memory: invalid quantity "-1": must be limited`))
+	})
+})
@@ -0,0 +1,88 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+)
+
+const (
+	// balanceAnnotation selects the load balancing algorithm of the OpenShift router for a Route.
+	balanceAnnotation = "haproxy.router.openshift.io/balance"
+	// h2cAppProtocol makes the OpenShift router talk HTTP/2 over cleartext to the Service port, like the Cloud
+	// Foundry router does with the applications that have HTTP/2 routes.
+	h2cAppProtocol = "h2c"
+)
+
+// balanceAlgorithms maps the Cloud Foundry load balancing types to the algorithms of the OpenShift router.
+var balanceAlgorithms = map[discover.LoadBalancingType]string{
+	discover.RoundRobinLoadBalancingType:      "roundrobin",
+	discover.LeastConnectionLoadBalancingType: "leastconn",
+}
+
+// openShiftRoutes returns an OpenShift Route for each HTTP route of the application, and one without host for its
+// random route, whose host is then generated by the router. The Routes terminate TLS at the router and redirect the
+// plain HTTP traffic to HTTPS.
+func openShiftRoutes(app discover.Application, namespace string) []Object {
+	if app.Routes.NoRoute {
+		return nil
+	}
+	name := ResourceName(app.Metadata.Name)
+	objects := []Object{}
+	add := func(host, path string, lb discover.LoadBalancingType) {
+		r := &Route{
+			TypeMeta: TypeMeta{APIVersion: "route.openshift.io/v1", Kind: "Route"},
+			Metadata: ObjectMeta{Name: name, Namespace: namespace, Labels: labels(app, discover.Web)},
+			Spec: RouteSpec{
+				Host: host,
+				Path: path,
+				To:   RouteTargetReference{Kind: "Service", Name: name},
+				Port: &RoutePort{TargetPort: portName},
+				TLS:  &TLSConfig{Termination: "edge", InsecureEdgeTerminationPolicy: "Redirect"},
+			},
+		}
+		if len(objects) > 0 {
+			r.Metadata.Name = ResourceName(fmt.Sprintf("%s-%d", name, len(objects)+1))
+		}
+		if algorithm, ok := balanceAlgorithms[lb]; ok {
+			r.Metadata.Annotations = map[string]string{balanceAnnotation: algorithm}
+		}
+		objects = append(objects, r)
+	}
+	for _, route := range app.Routes.Routes {
+		if route.Protocol == discover.TCPRouteProtocol {
+			continue
+		}
+		host, _, path := SplitRoute(route.Route)
+		add(host, path, route.Options.LoadBalancing)
+	}
+	if app.Routes.RandomRoute {
+		add("", "", "")
+	}
+	return objects
+}
+
+// usesHTTP2 returns true when the application has HTTP/2 routes, so the Cloud Foundry router talks HTTP/2 to it.
+func usesHTTP2(app discover.Application) bool {
+	for _, route := range app.Routes.Routes {
+		if route.Protocol == discover.HTTP2RouteProtocol {
+			return true
+		}
+	}
+	return false
+}
+
+// tcpRouteWarnings reports the TCP routes of the application, which can't be exposed with an Ingress or a Route and
+// need a Service of type LoadBalancer or NodePort instead.
+func tcpRouteWarnings(app discover.Application) []string {
+	if app.Routes.NoRoute {
+		return nil
+	}
+	var warnings []string
+	for _, route := range app.Routes.Routes {
+		if route.Protocol == discover.TCPRouteProtocol {
+			warnings = append(warnings, fmt.Sprintf("route %s of application %s is a TCP route: expose it with a Service of type LoadBalancer or NodePort", route.Route, app.Metadata.Name))
+		}
+	}
+	return warnings
+}
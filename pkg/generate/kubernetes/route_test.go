@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OpenShift routes", func() {
+	var app discover.Application
+
+	BeforeEach(func() {
+		app = sampleApplication()
+		app.Processes = app.Processes[:1]
+		app.Routes.Routes = discover.Routes{
+			{Route: "my-app.example.com", Options: discover.RouteOptions{LoadBalancing: discover.LeastConnectionLoadBalancingType}},
+			{Route: "www.example.com/my-app", Protocol: discover.HTTPRouteProtocol},
+			{Route: "tcp.example.com:1034", Protocol: discover.TCPRouteProtocol},
+		}
+	})
+
+	It("generates a Route per HTTP route with edge TLS termination and reports the TCP routes", func() {
+		r, err := Generate(app, Options{OpenShiftRoutes: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Warnings).To(Equal([]string{"route tcp.example.com:1034 of application My_App is a TCP route: expose it with a Service of type LoadBalancer or NodePort"}))
+		Expect(r.Objects).To(HaveLen(4))
+		Expect(render(r.Objects[2:])).To(Equal(`apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: my-app
+  namespace: dev
+  labels:
+    app.kubernetes.io/component: web
+    app.kubernetes.io/name: my-app
+  annotations:
+    haproxy.router.openshift.io/balance: leastconn
+spec:
+  host: my-app.example.com
+  to:
+    kind: Service
+    name: my-app
+  port:
+    targetPort: http
+  tls:
+    termination: edge
+    insecureEdgeTerminationPolicy: Redirect
+---
+apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: my-app-2
+  namespace: dev
+  labels:
+    app.kubernetes.io/component: web
+    app.kubernetes.io/name: my-app
+spec:
+  host: www.example.com
+  path: /my-app
+  to:
+    kind: Service
+    name: my-app
+  port:
+    targetPort: http
+  tls:
+    termination: edge
+    insecureEdgeTerminationPolicy: Redirect
+`))
+		Expect(r.Objects[1].(*Service).Spec.Ports[0].AppProtocol).To(BeEmpty())
+	})
+
+	It("talks HTTP/2 over cleartext to the applications with HTTP/2 routes", func() {
+		app.Routes.Routes[1].Protocol = discover.HTTP2RouteProtocol
+		r, err := Generate(app, Options{OpenShiftRoutes: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Objects[1].(*Service).Spec.Ports[0].AppProtocol).To(Equal("h2c"))
+
+		r, err = Generate(app, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Objects[1].(*Service).Spec.Ports[0].AppProtocol).To(BeEmpty())
+	})
+
+	It("lets the router generate the host of the random route", func() {
+		app.Routes = discover.RouteSpec{RandomRoute: true}
+		r, err := Generate(app, Options{OpenShiftRoutes: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Objects).To(HaveLen(3))
+		route := r.Objects[2].(*Route)
+		Expect(route.Metadata.Name).To(Equal("my-app"))
+		Expect(route.Spec.Host).To(BeEmpty())
+		Expect(route.Spec.To.Name).To(Equal("my-app"))
+	})
+
+	It("generates no Route for the applications without routes", func() {
+		app.Routes.NoRoute = true
+		r, err := Generate(app, Options{OpenShiftRoutes: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Objects).To(HaveLen(1))
+		Expect(r.Warnings).To(BeEmpty())
+	})
+})
@@ -0,0 +1,134 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+)
+
+const (
+	// bindingProvider is the provider entry of the generated binding Secrets.
+	bindingProvider = "cloudfoundry"
+	// bindingSecretTypePrefix prefixes the type of the binding Secrets, as recommended by the servicebinding.io
+	// specification.
+	bindingSecretTypePrefix = "servicebinding.io/"
+)
+
+// serviceTypes maps the schemes of the URIs in the credentials, and the words in the names of the services, to the
+// servicebinding.io types of the services. The entries are matched in order.
+var serviceTypes = []struct{ match, serviceType string }{
+	{"postgres", "postgresql"},
+	{"mysql", "mysql"},
+	{"mariadb", "mysql"},
+	{"redis", "redis"},
+	{"mongo", "mongodb"},
+	{"amqp", "rabbitmq"},
+	{"rabbit", "rabbitmq"},
+	{"kafka", "kafka"},
+}
+
+// BindingName returns the name of the binding of the service, which is also the name of the directory where its
+// credentials are projected. Cloud Foundry defaults it to the name of the service.
+func BindingName(s discover.ServiceSpec) string {
+	if s.BindingName != "" {
+		return s.BindingName
+	}
+	return s.Name
+}
+
+// BindingSecretName returns the name of the Secret with the credentials of the binding of the service to the
+// application. It is prefixed with the name of the application, since the applications bound to the same service
+// have different bindings.
+func BindingSecretName(app discover.Application, s discover.ServiceSpec) string {
+	return ResourceName(app.Metadata.Name + "-" + BindingName(s))
+}
+
+// serviceBindings returns a Secret and a servicebinding.io ServiceBinding for each service of the application. The
+// Secrets hold the type and provider entries of the binding and, when the application was discovered from the API
+// with its credentials, the credentials of the binding. The ServiceBindings project them into every Deployment of the
+// application, like Cloud Foundry binds the services to all the processes.
+func serviceBindings(app discover.Application, namespace string) ([]Object, []string) {
+	objects := []Object{}
+	var warnings []string
+	for _, s := range app.Services {
+		binding := BindingName(s)
+		secret := &Secret{
+			TypeMeta:   TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			Metadata:   ObjectMeta{Name: BindingSecretName(app, s), Namespace: namespace, Labels: map[string]string{nameLabel: ResourceName(app.Metadata.Name)}},
+			StringData: credentialEntries(s.Credentials),
+		}
+		t, ok := serviceType(s)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("the type of service %s of application %s is unknown: set the type entry of Secret %s", s.Name, app.Metadata.Name, secret.Metadata.Name))
+		}
+		if len(s.Credentials) == 0 {
+			warnings = append(warnings, fmt.Sprintf("Secret %s of service %s of application %s is a placeholder: add the credentials of the binding, or discover the application from the API with -include-credentials", secret.Metadata.Name, s.Name, app.Metadata.Name))
+		}
+		secret.Type = bindingSecretTypePrefix + t
+		secret.StringData["type"] = t
+		secret.StringData["provider"] = bindingProvider
+		objects = append(objects, secret, &ServiceBinding{
+			TypeMeta: TypeMeta{APIVersion: "servicebinding.io/v1beta1", Kind: "ServiceBinding"},
+			Metadata: ObjectMeta{Name: ResourceName(app.Metadata.Name + "-" + binding), Namespace: namespace, Labels: map[string]string{nameLabel: ResourceName(app.Metadata.Name)}},
+			Spec: ServiceBindingSpec{
+				Name:    binding,
+				Service: ServiceBindingServiceRef{APIVersion: "v1", Kind: "Secret", Name: secret.Metadata.Name},
+				Workload: ServiceBindingWorkloadRef{
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+					Selector:   &LabelSelector{MatchLabels: map[string]string{nameLabel: ResourceName(app.Metadata.Name)}},
+				},
+			},
+		})
+	}
+	return objects, warnings
+}
+
+// serviceType derives the servicebinding.io type of the service from the scheme of the URI in its credentials, or
+// from its name. It returns the name of the service and false when neither matches a known type.
+func serviceType(s discover.ServiceSpec) (string, bool) {
+	for _, key := range []string{"uri", "url", "jdbcUrl"} {
+		if v, ok := s.Credentials[key].(string); ok {
+			if u, err := url.Parse(strings.TrimPrefix(v, "jdbc:")); err == nil && u.Scheme != "" {
+				if t, ok := matchServiceType(u.Scheme); ok {
+					return t, true
+				}
+			}
+		}
+	}
+	if t, ok := matchServiceType(s.Name); ok {
+		return t, true
+	}
+	return ResourceName(s.Name), false
+}
+
+func matchServiceType(s string) (string, bool) {
+	s = strings.ToLower(s)
+	for _, t := range serviceTypes {
+		if strings.Contains(s, t.match) {
+			return t.serviceType, true
+		}
+	}
+	return "", false
+}
+
+// credentialEntries converts the credentials of a binding into the entries of its Secret. The values that are not
+// strings are encoded as JSON.
+func credentialEntries(credentials map[string]interface{}) map[string]string {
+	entries := map[string]string{}
+	for k, v := range credentials {
+		if s, ok := v.(string); ok {
+			entries[k] = s
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			b = []byte(fmt.Sprint(v))
+		}
+		entries[k] = string(b)
+	}
+	return entries
+}
@@ -0,0 +1,116 @@
+package kubernetes
+
+import (
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Service bindings", func() {
+	It("generates a Secret and a ServiceBinding per service, named after the application and the binding", func() {
+		app := sampleApplication()
+		app.Services = discover.Services{
+			{Name: "orders-db", BindingName: "db"},
+			{Name: "cache", Credentials: map[string]interface{}{"uri": "rediss://cache.example.com:6380", "port": 6380}},
+		}
+		r, err := Generate(app, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Warnings).To(Equal([]string{
+			"the type of service orders-db of application My_App is unknown: set the type entry of Secret my-app-db",
+			"Secret my-app-db of service orders-db of application My_App is a placeholder: add the credentials of the binding, or discover the application from the API with -include-credentials",
+		}))
+		Expect(r.Objects).To(HaveLen(8))
+		Expect(render(r.Objects[4:])).To(Equal(`apiVersion: v1
+kind: Secret
+metadata:
+  name: my-app-db
+  namespace: dev
+  labels:
+    app.kubernetes.io/name: my-app
+type: servicebinding.io/orders-db
+stringData:
+  provider: cloudfoundry
+  type: orders-db
+---
+apiVersion: servicebinding.io/v1beta1
+kind: ServiceBinding
+metadata:
+  name: my-app-db
+  namespace: dev
+  labels:
+    app.kubernetes.io/name: my-app
+spec:
+  name: db
+  service:
+    apiVersion: v1
+    kind: Secret
+    name: my-app-db
+  workload:
+    apiVersion: apps/v1
+    kind: Deployment
+    selector:
+      matchLabels:
+        app.kubernetes.io/name: my-app
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: my-app-cache
+  namespace: dev
+  labels:
+    app.kubernetes.io/name: my-app
+type: servicebinding.io/redis
+stringData:
+  port: "6380"
+  provider: cloudfoundry
+  type: redis
+  uri: rediss://cache.example.com:6380
+---
+apiVersion: servicebinding.io/v1beta1
+kind: ServiceBinding
+metadata:
+  name: my-app-cache
+  namespace: dev
+  labels:
+    app.kubernetes.io/name: my-app
+spec:
+  name: cache
+  service:
+    apiVersion: v1
+    kind: Secret
+    name: my-app-cache
+  workload:
+    apiVersion: apps/v1
+    kind: Deployment
+    selector:
+      matchLabels:
+        app.kubernetes.io/name: my-app
+`))
+	})
+
+	It("names the Secrets of the applications bound to the same service differently", func() {
+		names := []string{}
+		for _, name := range []string{"orders", "payments"} {
+			app := sampleApplication()
+			app.Metadata.Name = name
+			app.Services = discover.Services{{Name: "shared-db"}}
+			r, err := Generate(app, Options{})
+			Expect(err).NotTo(HaveOccurred())
+			secret := r.Objects[len(r.Objects)-2].(*Secret)
+			Expect(r.Objects[len(r.Objects)-1].(*ServiceBinding).Spec.Service.Name).To(Equal(secret.Metadata.Name))
+			names = append(names, secret.Metadata.Name)
+		}
+		Expect(names).To(Equal([]string{"orders-shared-db", "payments-shared-db"}))
+	})
+
+	DescribeTable("derives the type of the services", func(s discover.ServiceSpec, expected string, known bool) {
+		t, ok := serviceType(s)
+		Expect(t).To(Equal(expected))
+		Expect(ok).To(Equal(known))
+	},
+		Entry("from the scheme of the URI", discover.ServiceSpec{Name: "db", Credentials: map[string]interface{}{"uri": "postgres://db.example.com/orders"}}, "postgresql", true),
+		Entry("from a JDBC URL", discover.ServiceSpec{Name: "db", Credentials: map[string]interface{}{"jdbcUrl": "jdbc:mysql://db.example.com/orders"}}, "mysql", true),
+		Entry("from the name of the service", discover.ServiceSpec{Name: "Orders-MongoDB"}, "mongodb", true),
+		Entry("with an unknown service", discover.ServiceSpec{Name: "Payments_API"}, "payments-api", false),
+	)
+})
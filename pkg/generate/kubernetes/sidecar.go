@@ -0,0 +1,37 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+)
+
+// sidecarContainers returns a container for each sidecar that runs with the process, with the image and env of the
+// application.
+func sidecarContainers(app discover.Application, p discover.ProcessSpec, image string, policy ResourcePolicy) ([]Container, []string, error) {
+	containers := []Container{}
+	var warnings []string
+	for _, s := range app.SidecarsOf(p.Type) {
+		c := Container{
+			Name:    ResourceName(s.Name),
+			Image:   image,
+			Command: []string{"/bin/sh", "-c", s.Command},
+			Env:     env(app, p.Type),
+		}
+		if c.Name == ResourceName(string(p.Type)) {
+			c.Name = ResourceName(s.Name + "-sidecar")
+		}
+		if s.Memory == "" {
+			warnings = append(warnings, fmt.Sprintf("sidecar %s of application %s has no memory limit: it shares the memory of the %s process in Cloud Foundry, set its memory to limit it", s.Name, app.Metadata.Name, p.Type))
+			c.Resources = policy.sidecarResources(0)
+		} else {
+			memory, err := limitedBytes(s.Memory)
+			if err != nil {
+				return nil, nil, fmt.Errorf("sidecar %s: memory: %w", s.Name, err)
+			}
+			c.Resources = policy.sidecarResources(memory)
+		}
+		containers = append(containers, c)
+	}
+	return containers, warnings, nil
+}
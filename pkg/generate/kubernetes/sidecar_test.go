@@ -0,0 +1,76 @@
+package kubernetes
+
+import (
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sidecars", func() {
+	var app discover.Application
+
+	BeforeEach(func() {
+		app = sampleApplication()
+		app.Sidecars = discover.Sidecars{
+			{Name: "Proxy", Command: "./proxy", ProcessTypes: []discover.ProcessType{discover.Web}, Memory: "128M"},
+			{Name: "worker", Command: "./agent", ProcessTypes: []discover.ProcessType{discover.Web, discover.Worker}, Memory: "256M"},
+		}
+	})
+
+	It("runs each sidecar in the pods of its processes and takes its memory from the process", func() {
+		r, err := Generate(app, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Warnings).To(BeEmpty())
+
+		web := r.Objects[0].(*Deployment).Spec.Template.Spec.Containers
+		Expect(web).To(HaveLen(3))
+		Expect(web[0].Name).To(Equal("web"))
+		Expect(web[0].Resources.Limits).To(HaveKeyWithValue("memory", "128Mi"))
+		Expect(web[0].Resources.Limits).To(HaveKeyWithValue("ephemeral-storage", "1Gi"))
+		Expect(render([]Object{r.Objects[0]})).To(ContainSubstring(`        - name: proxy
+          image: registry.example.com/my-app:1.0
+          command:
+            - /bin/sh
+            - -c
+            - ./proxy
+          env:
+            - name: DB_HOST
+              value: db.example.com
+            - name: LOG_LEVEL
+              value: debug
+            - name: PORT
+              value: "8080"
+          resources:
+            limits:
+              memory: 128Mi
+            requests:
+              cpu: 16m
+              memory: 128Mi
+`))
+		Expect(web[2].Name).To(Equal("worker"))
+		Expect(web[2].Resources.Limits).To(Equal(map[string]string{"memory": "256Mi"}))
+
+		worker := r.Objects[1].(*Deployment).Spec.Template.Spec.Containers
+		Expect(worker).To(HaveLen(2))
+		Expect(worker[0].Resources.Limits).To(HaveKeyWithValue("memory", "768Mi"))
+		// The sidecar is renamed so that it does not clash with the container of the process.
+		Expect(worker[1].Name).To(Equal("worker-sidecar"))
+		Expect(worker[1].Command).To(Equal([]string{"/bin/sh", "-c", "./agent"}))
+	})
+
+	It("does not limit the memory of the sidecars without memory", func() {
+		app.Sidecars[0].Memory = ""
+		r, err := Generate(app, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Warnings).To(Equal([]string{"sidecar Proxy of application My_App has no memory limit: it shares the memory of the web process in Cloud Foundry, set its memory to limit it"}))
+		web := r.Objects[0].(*Deployment).Spec.Template.Spec.Containers
+		Expect(web[0].Resources.Limits).To(HaveKeyWithValue("memory", "256Mi"))
+		Expect(web[1].Resources).To(Equal(ResourceRequirements{Limits: map[string]string{}, Requests: map[string]string{"cpu": "10m"}}))
+	})
+
+	It("fails when the sidecars use all the memory of the process", func() {
+		app.Sidecars[1].Memory = "384M"
+		_, err := Generate(app, Options{})
+		Expect(err).To(MatchError("process web of application My_App: the sidecars of process web use 512M of memory, which exceeds the 512M of the process"))
+	})
+})
@@ -0,0 +1,278 @@
+package kubernetes
+
+// The types in this file are the subset of the Kubernetes API needed to render the generated manifests. They are
+// declared here to avoid depending on the Kubernetes client libraries, and only include the fields set by the
+// generators.
+
+// Object is a Kubernetes resource.
+type Object interface {
+	GetTypeMeta() TypeMeta
+	GetObjectMeta() *ObjectMeta
+}
+
+type TypeMeta struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+}
+
+func (t TypeMeta) GetTypeMeta() TypeMeta {
+	return t
+}
+
+type ObjectMeta struct {
+	Name        string            `yaml:"name,omitempty"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type Deployment struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta     `yaml:"metadata"`
+	Spec     DeploymentSpec `yaml:"spec"`
+}
+
+func (d *Deployment) GetObjectMeta() *ObjectMeta {
+	return &d.Metadata
+}
+
+type DeploymentSpec struct {
+	Replicas int             `yaml:"replicas"`
+	Selector LabelSelector   `yaml:"selector"`
+	Template PodTemplateSpec `yaml:"template"`
+}
+
+type LabelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+type PodTemplateSpec struct {
+	Metadata ObjectMeta `yaml:"metadata"`
+	Spec     PodSpec    `yaml:"spec"`
+}
+
+type PodSpec struct {
+	Containers []Container `yaml:"containers"`
+}
+
+type Container struct {
+	Name      string               `yaml:"name"`
+	Image     string               `yaml:"image"`
+	Command   []string             `yaml:"command,omitempty"`
+	Ports     []ContainerPort      `yaml:"ports,omitempty"`
+	Env       []EnvVar             `yaml:"env,omitempty"`
+	Resources ResourceRequirements `yaml:"resources,omitempty"`
+	// LivenessProbe, ReadinessProbe and StartupProbe are omitted when nil.
+	LivenessProbe  *Probe `yaml:"livenessProbe,omitempty"`
+	ReadinessProbe *Probe `yaml:"readinessProbe,omitempty"`
+	StartupProbe   *Probe `yaml:"startupProbe,omitempty"`
+}
+
+type Probe struct {
+	ProbeHandler     `yaml:",inline"`
+	PeriodSeconds    int `yaml:"periodSeconds,omitempty"`
+	TimeoutSeconds   int `yaml:"timeoutSeconds,omitempty"`
+	FailureThreshold int `yaml:"failureThreshold,omitempty"`
+}
+
+// ProbeHandler holds the action of a probe. Only one of the fields is set.
+type ProbeHandler struct {
+	HTTPGet   *HTTPGetAction   `yaml:"httpGet,omitempty"`
+	TCPSocket *TCPSocketAction `yaml:"tcpSocket,omitempty"`
+}
+
+type HTTPGetAction struct {
+	Path string `yaml:"path"`
+	Port int    `yaml:"port"`
+}
+
+type TCPSocketAction struct {
+	Port int `yaml:"port"`
+}
+
+type ContainerPort struct {
+	Name          string `yaml:"name,omitempty"`
+	ContainerPort int    `yaml:"containerPort"`
+	Protocol      string `yaml:"protocol,omitempty"`
+}
+
+type EnvVar struct {
+	Name      string        `yaml:"name"`
+	Value     string        `yaml:"value,omitempty"`
+	ValueFrom *EnvVarSource `yaml:"valueFrom,omitempty"`
+}
+
+type EnvVarSource struct {
+	FieldRef        *ObjectFieldSelector `yaml:"fieldRef,omitempty"`
+	ConfigMapKeyRef *KeySelector         `yaml:"configMapKeyRef,omitempty"`
+	SecretKeyRef    *KeySelector         `yaml:"secretKeyRef,omitempty"`
+}
+
+type ObjectFieldSelector struct {
+	FieldPath string `yaml:"fieldPath"`
+}
+
+// KeySelector selects an entry of a ConfigMap or a Secret.
+type KeySelector struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+type ResourceRequirements struct {
+	Limits   map[string]string `yaml:"limits,omitempty"`
+	Requests map[string]string `yaml:"requests,omitempty"`
+}
+
+type Service struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta  `yaml:"metadata"`
+	Spec     ServiceSpec `yaml:"spec"`
+}
+
+func (s *Service) GetObjectMeta() *ObjectMeta {
+	return &s.Metadata
+}
+
+type ServiceSpec struct {
+	Type     string            `yaml:"type,omitempty"`
+	Selector map[string]string `yaml:"selector"`
+	Ports    []ServicePort     `yaml:"ports"`
+}
+
+type ServicePort struct {
+	Name        string `yaml:"name"`
+	Protocol    string `yaml:"protocol,omitempty"`
+	AppProtocol string `yaml:"appProtocol,omitempty"`
+	Port        int    `yaml:"port"`
+	TargetPort  string `yaml:"targetPort"`
+}
+
+type Ingress struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta  `yaml:"metadata"`
+	Spec     IngressSpec `yaml:"spec"`
+}
+
+func (i *Ingress) GetObjectMeta() *ObjectMeta {
+	return &i.Metadata
+}
+
+type IngressSpec struct {
+	IngressClassName string        `yaml:"ingressClassName,omitempty"`
+	Rules            []IngressRule `yaml:"rules"`
+}
+
+type IngressRule struct {
+	Host string               `yaml:"host,omitempty"`
+	HTTP HTTPIngressRuleValue `yaml:"http"`
+}
+
+type HTTPIngressRuleValue struct {
+	Paths []HTTPIngressPath `yaml:"paths"`
+}
+
+type HTTPIngressPath struct {
+	Path     string         `yaml:"path"`
+	PathType string         `yaml:"pathType"`
+	Backend  IngressBackend `yaml:"backend"`
+}
+
+type IngressBackend struct {
+	Service IngressServiceBackend `yaml:"service"`
+}
+
+type IngressServiceBackend struct {
+	Name string             `yaml:"name"`
+	Port ServiceBackendPort `yaml:"port"`
+}
+
+type ServiceBackendPort struct {
+	Name string `yaml:"name"`
+}
+
+// Route is an OpenShift Route, which exposes a Service through the OpenShift router.
+type Route struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta `yaml:"metadata"`
+	Spec     RouteSpec  `yaml:"spec"`
+}
+
+func (r *Route) GetObjectMeta() *ObjectMeta {
+	return &r.Metadata
+}
+
+type RouteSpec struct {
+	// Host is generated by the router when empty.
+	Host string               `yaml:"host,omitempty"`
+	Path string               `yaml:"path,omitempty"`
+	To   RouteTargetReference `yaml:"to"`
+	Port *RoutePort           `yaml:"port,omitempty"`
+	TLS  *TLSConfig           `yaml:"tls,omitempty"`
+}
+
+type RouteTargetReference struct {
+	Kind string `yaml:"kind"`
+	Name string `yaml:"name"`
+}
+
+type RoutePort struct {
+	TargetPort string `yaml:"targetPort"`
+}
+
+type TLSConfig struct {
+	Termination                   string `yaml:"termination"`
+	InsecureEdgeTerminationPolicy string `yaml:"insecureEdgeTerminationPolicy,omitempty"`
+}
+
+type Secret struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta `yaml:"metadata"`
+	Type     string     `yaml:"type,omitempty"`
+	// StringData holds the entries of the Secret as plain text, which the API server encodes into its data.
+	StringData map[string]string `yaml:"stringData,omitempty"`
+}
+
+func (s *Secret) GetObjectMeta() *ObjectMeta {
+	return &s.Metadata
+}
+
+type ConfigMap struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta        `yaml:"metadata"`
+	Data     map[string]string `yaml:"data,omitempty"`
+}
+
+func (c *ConfigMap) GetObjectMeta() *ObjectMeta {
+	return &c.Metadata
+}
+
+// ServiceBinding is a servicebinding.io ServiceBinding, which projects the entries of a Secret into the containers of
+// a workload.
+type ServiceBinding struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta         `yaml:"metadata"`
+	Spec     ServiceBindingSpec `yaml:"spec"`
+}
+
+func (b *ServiceBinding) GetObjectMeta() *ObjectMeta {
+	return &b.Metadata
+}
+
+type ServiceBindingSpec struct {
+	// Name is the name of the directory of the binding in the containers. Defaults to the name of the ServiceBinding.
+	Name     string                    `yaml:"name,omitempty"`
+	Service  ServiceBindingServiceRef  `yaml:"service"`
+	Workload ServiceBindingWorkloadRef `yaml:"workload"`
+}
+
+type ServiceBindingServiceRef struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Name       string `yaml:"name"`
+}
+
+type ServiceBindingWorkloadRef struct {
+	APIVersion string         `yaml:"apiVersion"`
+	Kind       string         `yaml:"kind"`
+	Selector   *LabelSelector `yaml:"selector,omitempty"`
+}
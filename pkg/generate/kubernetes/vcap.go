@@ -0,0 +1,218 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+)
+
+const (
+	// userProvidedLabel is the label of the user-provided services in VCAP_SERVICES, which is also used for the
+	// services whose offering is unknown.
+	userProvidedLabel = "user-provided"
+	// fileDescriptorLimit is the limit of file descriptors of the Cloud Foundry containers.
+	fileDescriptorLimit = 16384
+	// instancePorts is the CF_INSTANCE_PORTS variable of the web processes.
+	instancePorts = `[{"external":8080,"internal":8080}]`
+)
+
+// vcapService is an entry of VCAP_SERVICES.
+type vcapService struct {
+	Name         string                 `json:"name"`
+	InstanceName string                 `json:"instance_name"`
+	BindingName  *string                `json:"binding_name"`
+	Label        string                 `json:"label"`
+	Tags         []string               `json:"tags"`
+	Credentials  map[string]interface{} `json:"credentials"`
+}
+
+// vcapApplication is the content of VCAP_APPLICATION.
+type vcapApplication struct {
+	ApplicationName  string     `json:"application_name"`
+	Name             string     `json:"name"`
+	ApplicationURIs  []string   `json:"application_uris"`
+	URIs             []string   `json:"uris"`
+	SpaceName        string     `json:"space_name,omitempty"`
+	OrganizationName string     `json:"organization_name,omitempty"`
+	ProcessType      string     `json:"process_type"`
+	Limits           vcapLimits `json:"limits"`
+}
+
+type vcapLimits struct {
+	// Mem and Disk are in megabytes.
+	Mem  int64 `json:"mem"`
+	Disk int64 `json:"disk"`
+	FDs  int   `json:"fds"`
+}
+
+// VCAPServices returns the VCAP_SERVICES variable of the application: its services grouped by label, with the
+// credentials of their bindings when the application was discovered from the API with them. The label is the
+// servicebinding.io type of the service, or `user-provided` when it is unknown.
+func VCAPServices(app discover.Application) (string, error) {
+	services := map[string][]vcapService{}
+	for _, s := range app.Services {
+		label, ok := serviceType(s)
+		if !ok {
+			label = userProvidedLabel
+		}
+		entry := vcapService{Name: BindingName(s), InstanceName: s.Name, Label: label, Tags: []string{}, Credentials: s.Credentials}
+		if s.BindingName != "" {
+			entry.BindingName = &s.BindingName
+		}
+		if entry.Credentials == nil {
+			entry.Credentials = map[string]interface{}{}
+		}
+		services[label] = append(services[label], entry)
+	}
+	b, err := json.Marshal(services)
+	return string(b), err
+}
+
+// VCAPApplication returns the VCAP_APPLICATION variable of the process. The GUIDs of the application, the space and
+// the organization are unknown outside of Cloud Foundry and are left out.
+func VCAPApplication(app discover.Application, p discover.ProcessSpec) (string, error) {
+	memory, err := limitedBytes(p.Memory)
+	if err != nil {
+		return "", fmt.Errorf("memory: %w", err)
+	}
+	disk := p.DiskQuota
+	if disk == "" {
+		disk = defaultDiskQuota
+	}
+	storage, err := limitedBytes(disk)
+	if err != nil {
+		return "", fmt.Errorf("disk quota: %w", err)
+	}
+	uris := []string{}
+	if !app.Routes.NoRoute {
+		for _, route := range app.Routes.Routes {
+			uris = append(uris, route.Route)
+		}
+	}
+	b, err := json.Marshal(vcapApplication{
+		ApplicationName:  app.Metadata.Name,
+		Name:             app.Metadata.Name,
+		ApplicationURIs:  uris,
+		URIs:             uris,
+		SpaceName:        app.Metadata.Space,
+		OrganizationName: app.Metadata.Organization,
+		ProcessType:      string(p.Type),
+		Limits:           vcapLimits{Mem: memory / discover.Megabyte, Disk: storage / discover.Megabyte, FDs: fileDescriptorLimit},
+	})
+	return string(b), err
+}
+
+// vcapName is the name of the Secret with VCAP_SERVICES and of the ConfigMap with the VCAP_APPLICATION of each
+// process of the application.
+func vcapName(app discover.Application) string {
+	return ResourceName(app.Metadata.Name + "-vcap")
+}
+
+// vcapObjects returns the Secret with the VCAP_SERVICES of the application and the ConfigMap with the
+// VCAP_APPLICATION of each of its processes, keyed by process type.
+func vcapObjects(app discover.Application, namespace string) ([]Object, error) {
+	services, err := VCAPServices(app)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]string{}
+	for _, p := range Processes(app) {
+		if data[ResourceName(string(p.Type))], err = VCAPApplication(app, p); err != nil {
+			return nil, fmt.Errorf("process %s of application %s: %w", p.Type, app.Metadata.Name, err)
+		}
+	}
+	meta := ObjectMeta{Name: vcapName(app), Namespace: namespace, Labels: map[string]string{nameLabel: ResourceName(app.Metadata.Name)}}
+	return []Object{
+		&Secret{TypeMeta: TypeMeta{APIVersion: "v1", Kind: "Secret"}, Metadata: meta, StringData: map[string]string{"VCAP_SERVICES": services}},
+		&ConfigMap{TypeMeta: TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}, Metadata: meta, Data: data},
+	}, nil
+}
+
+// cfEnv returns the variables that Cloud Foundry sets in the containers of the process, other than PORT, which are
+// added after the variables of the application in this order so that CF_INSTANCE_ADDR can reference
+// CF_INSTANCE_IP. VCAP_SERVICES and VCAP_APPLICATION reference the objects returned by vcapObjects, and the
+// CF_INSTANCE_* variables are read from the pod. CF_INSTANCE_INDEX is only set for the processes with a single
+// instance, since the pods of a Deployment have no index.
+func cfEnv(app discover.Application, p discover.ProcessSpec) []EnvVar {
+	field := func(path string) *EnvVarSource {
+		return &EnvVarSource{FieldRef: &ObjectFieldSelector{FieldPath: path}}
+	}
+	vars := []EnvVar{
+		{Name: "CF_INSTANCE_GUID", ValueFrom: field("metadata.uid")},
+		{Name: "CF_INSTANCE_IP", ValueFrom: field("status.podIP")},
+		{Name: "CF_INSTANCE_INTERNAL_IP", ValueFrom: field("status.podIP")},
+	}
+	if p.Type == discover.Web {
+		vars = append(vars,
+			EnvVar{Name: "CF_INSTANCE_PORT", Value: strconv.Itoa(DefaultPort)},
+			EnvVar{Name: "CF_INSTANCE_ADDR", Value: fmt.Sprintf("$(CF_INSTANCE_IP):%d", DefaultPort)},
+			EnvVar{Name: "CF_INSTANCE_PORTS", Value: instancePorts},
+		)
+	}
+	if p.Instances == 1 {
+		vars = append(vars, EnvVar{Name: "CF_INSTANCE_INDEX", Value: "0"})
+	}
+	vars = append(vars,
+		EnvVar{Name: "MEMORY_LIMIT", Value: memoryLimit(p)},
+		EnvVar{Name: "VCAP_APPLICATION", ValueFrom: &EnvVarSource{ConfigMapKeyRef: &KeySelector{Name: vcapName(app), Key: ResourceName(string(p.Type))}}},
+		EnvVar{Name: "VCAP_SERVICES", ValueFrom: &EnvVarSource{SecretKeyRef: &KeySelector{Name: vcapName(app), Key: "VCAP_SERVICES"}}},
+	)
+	return withoutOverrides(app, vars)
+}
+
+// EnvFile returns the variables of the process in the format of the `--env-file` flag of `docker run`: the variables
+// of the application followed by the ones set by Cloud Foundry, with VCAP_SERVICES and VCAP_APPLICATION inlined. The
+// container runs as the first instance of the process, and the variables with the address of the instance are left
+// out since it is only known inside the container.
+func EnvFile(app discover.Application, p discover.ProcessSpec) ([]byte, error) {
+	application, err := VCAPApplication(app, p)
+	if err != nil {
+		return nil, fmt.Errorf("process %s of application %s: %w", p.Type, app.Metadata.Name, err)
+	}
+	services, err := VCAPServices(app)
+	if err != nil {
+		return nil, err
+	}
+	vars := []EnvVar{}
+	if p.Type == discover.Web {
+		vars = append(vars,
+			EnvVar{Name: "CF_INSTANCE_PORT", Value: strconv.Itoa(DefaultPort)},
+			EnvVar{Name: "CF_INSTANCE_PORTS", Value: instancePorts},
+		)
+	}
+	vars = append(vars,
+		EnvVar{Name: "CF_INSTANCE_INDEX", Value: "0"},
+		EnvVar{Name: "MEMORY_LIMIT", Value: memoryLimit(p)},
+		EnvVar{Name: "VCAP_APPLICATION", Value: application},
+		EnvVar{Name: "VCAP_SERVICES", Value: services},
+	)
+	b := bytes.Buffer{}
+	for _, v := range append(env(app, p.Type), withoutOverrides(app, vars)...) {
+		if strings.ContainsAny(v.Value, "\r\n") {
+			return nil, fmt.Errorf("variable %s of application %s has a value with several lines, which env files don't support", v.Name, app.Metadata.Name)
+		}
+		fmt.Fprintf(&b, "%s=%s\n", v.Name, v.Value)
+	}
+	return b.Bytes(), nil
+}
+
+// withoutOverrides removes the variables set by the application from vars, since they take precedence.
+func withoutOverrides(app discover.Application, vars []EnvVar) []EnvVar {
+	filtered := []EnvVar{}
+	for _, v := range vars {
+		if _, ok := app.Env[v.Name]; !ok {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
+
+// memoryLimit returns the MEMORY_LIMIT variable of the process, which is its memory in megabytes.
+func memoryLimit(p discover.ProcessSpec) string {
+	memory, _ := limitedBytes(p.Memory)
+	return fmt.Sprintf("%dm", memory/discover.Megabyte)
+}
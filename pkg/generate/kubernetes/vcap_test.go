@@ -0,0 +1,95 @@
+package kubernetes
+
+import (
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cloud Foundry variables", func() {
+	var app discover.Application
+
+	BeforeEach(func() {
+		app = sampleApplication()
+		app.Metadata.Organization = "acme"
+		app.Routes.Routes = append(app.Routes.Routes, discover.Route{Route: "tcp.example.com:1034", Protocol: discover.TCPRouteProtocol})
+		app.Services = discover.Services{
+			{Name: "orders-db", BindingName: "db", Credentials: map[string]interface{}{"uri": "postgres://db.example.com/orders"}},
+			{Name: "payments"},
+		}
+	})
+
+	It("synthesizes VCAP_SERVICES with the credentials of the bindings", func() {
+		Expect(VCAPServices(app)).To(Equal(`{"postgresql":[{"name":"db","instance_name":"orders-db","binding_name":"db","label":"postgresql","tags":[],"credentials":{"uri":"postgres://db.example.com/orders"}}],` +
+			`"user-provided":[{"name":"payments","instance_name":"payments","binding_name":null,"label":"user-provided","tags":[],"credentials":{}}]}`))
+	})
+
+	It("synthesizes VCAP_APPLICATION for each process", func() {
+		Expect(VCAPApplication(app, app.Processes[0])).To(Equal(`{"application_name":"My_App","name":"My_App",` +
+			`"application_uris":["my-app.example.com","tcp.example.com:1034"],"uris":["my-app.example.com","tcp.example.com:1034"],` +
+			`"space_name":"dev","organization_name":"acme","process_type":"web","limits":{"mem":512,"disk":1024,"fds":16384}}`))
+	})
+
+	It("references the variables from a Secret and a ConfigMap in the containers", func() {
+		r, err := Generate(app, Options{CFEnv: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Warnings).To(ContainElement("process web of application My_App runs 3 instances: CF_INSTANCE_INDEX is not set, since the pods of a Deployment have no index"))
+		Expect(r.Objects).To(HaveLen(10))
+		Expect(render(r.Objects[8:])).To(Equal(`apiVersion: v1
+kind: Secret
+metadata:
+  name: my-app-vcap
+  namespace: dev
+  labels:
+    app.kubernetes.io/name: my-app
+stringData:
+  VCAP_SERVICES: '{"postgresql":[{"name":"db","instance_name":"orders-db","binding_name":"db","label":"postgresql","tags":[],"credentials":{"uri":"postgres://db.example.com/orders"}}],"user-provided":[{"name":"payments","instance_name":"payments","binding_name":null,"label":"user-provided","tags":[],"credentials":{}}]}'
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-app-vcap
+  namespace: dev
+  labels:
+    app.kubernetes.io/name: my-app
+data:
+  web: '{"application_name":"My_App","name":"My_App","application_uris":["my-app.example.com","tcp.example.com:1034"],"uris":["my-app.example.com","tcp.example.com:1034"],"space_name":"dev","organization_name":"acme","process_type":"web","limits":{"mem":512,"disk":1024,"fds":16384}}'
+  worker: '{"application_name":"My_App","name":"My_App","application_uris":["my-app.example.com","tcp.example.com:1034"],"uris":["my-app.example.com","tcp.example.com:1034"],"space_name":"dev","organization_name":"acme","process_type":"worker","limits":{"mem":1024,"disk":1024,"fds":16384}}'
+`))
+		worker := r.Objects[1].(*Deployment).Spec.Template.Spec.Containers[0]
+		Expect(worker.Env).To(Equal([]EnvVar{
+			{Name: "DB_HOST", Value: "db.example.com"},
+			{Name: "LOG_LEVEL", Value: "debug"},
+			{Name: "CF_INSTANCE_GUID", ValueFrom: &EnvVarSource{FieldRef: &ObjectFieldSelector{FieldPath: "metadata.uid"}}},
+			{Name: "CF_INSTANCE_IP", ValueFrom: &EnvVarSource{FieldRef: &ObjectFieldSelector{FieldPath: "status.podIP"}}},
+			{Name: "CF_INSTANCE_INTERNAL_IP", ValueFrom: &EnvVarSource{FieldRef: &ObjectFieldSelector{FieldPath: "status.podIP"}}},
+			{Name: "CF_INSTANCE_INDEX", Value: "0"},
+			{Name: "MEMORY_LIMIT", Value: "1024m"},
+			{Name: "VCAP_APPLICATION", ValueFrom: &EnvVarSource{ConfigMapKeyRef: &KeySelector{Name: "my-app-vcap", Key: "worker"}}},
+			{Name: "VCAP_SERVICES", ValueFrom: &EnvVarSource{SecretKeyRef: &KeySelector{Name: "my-app-vcap", Key: "VCAP_SERVICES"}}},
+		}))
+	})
+
+	It("writes the variables of a process to an env file", func() {
+		app.Env["MEMORY_LIMIT"] = "256m"
+		content, err := EnvFile(app, app.Processes[0])
+		Expect(err).NotTo(HaveOccurred())
+		services, _ := VCAPServices(app)
+		application, _ := VCAPApplication(app, app.Processes[0])
+		Expect(string(content)).To(Equal(`DB_HOST=db.example.com
+LOG_LEVEL=debug
+MEMORY_LIMIT=256m
+PORT=8080
+CF_INSTANCE_PORT=8080
+CF_INSTANCE_PORTS=[{"external":8080,"internal":8080}]
+CF_INSTANCE_INDEX=0
+VCAP_APPLICATION=` + application + `
+VCAP_SERVICES=` + services + "\n"))
+	})
+
+	It("rejects values with several lines in env files", func() {
+		app.Env["CERTIFICATE"] = "-----BEGIN CERTIFICATE-----\n..."
+		_, err := EnvFile(app, app.Processes[0])
+		Expect(err).To(MatchError("variable CERTIFICATE of application My_App has a value with several lines, which env files don't support"))
+	})
+})
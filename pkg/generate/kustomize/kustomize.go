@@ -0,0 +1,418 @@
+package kustomize
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	"github.com/gciavarrini/cf-application-discovery/pkg/generate/kubernetes"
+	"github.com/gciavarrini/cf-application-discovery/pkg/output"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	kustomizationFile = "kustomization.yaml"
+	baseDir           = "base"
+	overlaysDir       = "overlays"
+)
+
+// mergeKeys are the patch merge keys of the lists that the strategic merge of Kustomize merges item by item, by kind
+// of resource and path of the list. The other lists, including all the lists of the custom resources like the
+// ServiceBindings and the OpenShift Routes, which have no patch strategy, are replaced as a whole.
+var mergeKeys = map[string]map[string]string{
+	"Deployment": {
+		"spec.template.spec.containers":              "name",
+		"spec.template.spec.containers.env":          "name",
+		"spec.template.spec.containers.ports":        "containerPort",
+		"spec.template.spec.containers.volumeMounts": "mountPath",
+		"spec.template.spec.volumes":                 "name",
+	},
+	"Service": {
+		"spec.ports": "port",
+	},
+}
+
+// Options configures the generation of the Kustomize layouts.
+type Options struct {
+	// Kubernetes configures the translation of each variant of the applications into Kubernetes resources. The
+	// namespace, when set, replaces the space as the namespace of every overlay.
+	Kubernetes kubernetes.Options
+}
+
+// Layout is the Kustomize layout generated for an application: a base with the resources shared by all its
+// variants, and an overlay per variant with the differences.
+type Layout struct {
+	// Name is the name of the application, which is also the name of the directory of the layout.
+	Name string
+	// Files maps the path of each file in the layout, relative to its directory, to its contents.
+	Files map[string][]byte
+}
+
+// Result contains the generated layouts.
+type Result struct {
+	Layouts []Layout
+	// Warnings describe the parts of the applications that need manual changes after the generation.
+	Warnings []string
+}
+
+type kustomization struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Namespace  string   `yaml:"namespace,omitempty"`
+	Resources  []string `yaml:"resources,omitempty"`
+	Patches    []patch  `yaml:"patches,omitempty"`
+}
+
+type patch struct {
+	Path string `yaml:"path"`
+}
+
+// document is a Kubernetes resource in its generic YAML form.
+type document = map[string]interface{}
+
+// variant holds the resources generated for an application in one of its spaces.
+type variant struct {
+	name      string
+	namespace string
+	keys      []string
+	documents map[string]document
+}
+
+// Generate builds a Kustomize layout for each application. The applications with the same name are the variants of
+// the same application deployed to different spaces, like dev, stage and prod: the resources they share go to the
+// base, and each space gets an overlay, named after the space, that adds or patches the fields that differ, such as
+// the instances, memory, routes and env.
+func Generate(apps []discover.Application, opts Options) (*Result, error) {
+	r := &Result{}
+	names := []string{}
+	groups := map[string][]discover.Application{}
+	for _, app := range apps {
+		name := kubernetes.ResourceName(app.Metadata.Name)
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], app)
+	}
+	for _, name := range names {
+		variants := []variant{}
+		for _, app := range groups[name] {
+			if app.Metadata.Space == "" {
+				return nil, fmt.Errorf("application %s has no space: the space is required to name its overlay", app.Metadata.Name)
+			}
+			v, warnings, err := newVariant(app, opts)
+			if err != nil {
+				return nil, err
+			}
+			if slices.ContainsFunc(variants, func(o variant) bool { return o.name == v.name }) {
+				return nil, fmt.Errorf("application %s is defined more than once in space %s", app.Metadata.Name, app.Metadata.Space)
+			}
+			variants = append(variants, v)
+			for _, w := range warnings {
+				if !slices.Contains(r.Warnings, w) {
+					r.Warnings = append(r.Warnings, w)
+				}
+			}
+		}
+		l, err := layout(name, variants)
+		if err != nil {
+			return nil, err
+		}
+		r.Layouts = append(r.Layouts, l)
+	}
+	return r, nil
+}
+
+func newVariant(app discover.Application, opts Options) (variant, []string, error) {
+	k, err := kubernetes.Generate(app, opts.Kubernetes)
+	if err != nil {
+		return variant{}, nil, err
+	}
+	v := variant{
+		name:      kubernetes.ResourceName(app.Metadata.Space),
+		namespace: opts.Kubernetes.Namespace,
+		documents: map[string]document{},
+	}
+	if v.namespace == "" {
+		v.namespace = v.name
+	}
+	b := bytes.Buffer{}
+	if err := output.Encode(&b, output.YAMLFormat, k.Objects); err != nil {
+		return variant{}, nil, err
+	}
+	dec := yaml.NewDecoder(&b)
+	for _, o := range k.Objects {
+		doc := document{}
+		if err := dec.Decode(&doc); err != nil {
+			return variant{}, nil, err
+		}
+		// The namespace is set by the overlay.
+		delete(doc["metadata"].(document), "namespace")
+		key := fileName(o)
+		v.keys = append(v.keys, key)
+		v.documents[key] = doc
+	}
+	return v, k.Warnings, nil
+}
+
+// layout splits the resources of the variants into the base and the overlays. A resource generated for every variant
+// goes to the base with the fields that all the variants share, and each overlay patches the remaining fields. The
+// resources generated only for some of the variants, like the Service of an application that has no routes in some
+// spaces, are added by the overlays of those variants.
+func layout(name string, variants []variant) (Layout, error) {
+	l := Layout{Name: name, Files: map[string][]byte{}}
+	base := kustomization{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization", Resources: []string{}}
+	shared := map[string]document{}
+	for _, key := range variants[0].keys {
+		doc := variants[0].documents[key]
+		for _, v := range variants[1:] {
+			other, ok := v.documents[key]
+			if !ok {
+				doc = nil
+				break
+			}
+			doc, _ = intersect(doc, other, mergeKeys[doc["kind"].(string)], "").(document)
+		}
+		if doc == nil {
+			continue
+		}
+		shared[key] = doc
+		base.Resources = append(base.Resources, key)
+		if err := l.add(path.Join(baseDir, key), doc); err != nil {
+			return Layout{}, err
+		}
+	}
+	if err := l.add(path.Join(baseDir, kustomizationFile), base); err != nil {
+		return Layout{}, err
+	}
+
+	for _, v := range variants {
+		dir := path.Join(overlaysDir, v.name)
+		overlay := kustomization{
+			APIVersion: base.APIVersion,
+			Kind:       base.Kind,
+			Namespace:  v.namespace,
+			Resources:  []string{"../../" + baseDir},
+		}
+		for _, key := range v.keys {
+			doc := v.documents[key]
+			b, ok := shared[key]
+			if !ok {
+				overlay.Resources = append(overlay.Resources, key)
+				if err := l.add(path.Join(dir, key), doc); err != nil {
+					return Layout{}, err
+				}
+				continue
+			}
+			d := diff(b, doc, mergeKeys[doc["kind"].(string)], "")
+			if d == nil {
+				continue
+			}
+			// Kustomize finds the resource to patch from its kind and name.
+			p := d.(document)
+			metadata, _ := p["metadata"].(document)
+			if metadata == nil {
+				metadata = document{}
+			}
+			metadata["name"] = doc["metadata"].(document)["name"]
+			p["metadata"] = metadata
+			p["apiVersion"] = doc["apiVersion"]
+			p["kind"] = doc["kind"]
+			overlay.Patches = append(overlay.Patches, patch{Path: key})
+			if err := l.add(path.Join(dir, key), p); err != nil {
+				return Layout{}, err
+			}
+		}
+		if err := l.add(path.Join(dir, kustomizationFile), overlay); err != nil {
+			return Layout{}, err
+		}
+	}
+	return l, nil
+}
+
+// fileName returns the name of the file of the resource, like `deployment-my-app.yaml`.
+func fileName(o kubernetes.Object) string {
+	return strings.ToLower(o.GetTypeMeta().Kind) + "-" + o.GetObjectMeta().Name + ".yaml"
+}
+
+// intersect returns the fields that a and b have in common, or nil when they have none. Maps are intersected key by
+// key, and the lists at the paths with a merge key in keys item by item. Other values, including the other lists, are
+// only kept when they are equal.
+func intersect(a, b interface{}, keys map[string]string, path string) interface{} {
+	switch a := a.(type) {
+	case document:
+		b, ok := b.(document)
+		if !ok {
+			return nil
+		}
+		if len(a) == 0 && len(b) == 0 {
+			return document{}
+		}
+		r := document{}
+		for k, av := range a {
+			bv, ok := b[k]
+			if !ok {
+				continue
+			}
+			if v := intersect(av, bv, keys, joinPath(path, k)); v != nil {
+				r[k] = v
+			}
+		}
+		if len(r) == 0 {
+			return nil
+		}
+		return r
+	case []interface{}:
+		b, ok := b.([]interface{})
+		key := mergeKey(keys[path], a, b)
+		if !ok || key == "" {
+			break
+		}
+		r := []interface{}{}
+		for _, item := range a {
+			other := find(b, key, item.(document)[key])
+			if other == nil {
+				continue
+			}
+			v, _ := intersect(item, other, keys, path).(document)
+			// An item is only shared when more than its key is, otherwise the overlays add it as a whole.
+			if v != nil && (len(v) > 1 || len(item.(document)) == 1) {
+				r = append(r, v)
+			}
+		}
+		if len(r) == 0 {
+			return nil
+		}
+		return r
+	}
+	if reflect.DeepEqual(a, b) {
+		return a
+	}
+	return nil
+}
+
+// diff returns the strategic merge patch that turns base into v, or nil when they are equal, using the merge keys of
+// the lists in keys. base must be the intersection of v with other values, so the patch never needs to remove fields.
+func diff(base, v interface{}, keys map[string]string, path string) interface{} {
+	switch v := v.(type) {
+	case document:
+		b, ok := base.(document)
+		if !ok {
+			return v
+		}
+		r := document{}
+		for k, value := range v {
+			bv, ok := b[k]
+			if !ok {
+				r[k] = value
+				continue
+			}
+			if d := diff(bv, value, keys, joinPath(path, k)); d != nil {
+				r[k] = d
+			}
+		}
+		if len(r) == 0 {
+			return nil
+		}
+		return r
+	case []interface{}:
+		b, ok := base.([]interface{})
+		key := mergeKey(keys[path], b, v)
+		if !ok || key == "" {
+			break
+		}
+		r := []interface{}{}
+		for _, item := range v {
+			name := item.(document)[key]
+			baseItem := find(b, key, name)
+			if baseItem == nil {
+				r = append(r, item)
+				continue
+			}
+			if d := diff(baseItem, item, keys, path); d != nil {
+				d.(document)[key] = name
+				r = append(r, d)
+			}
+		}
+		if len(r) == 0 {
+			return nil
+		}
+		return r
+	}
+	if reflect.DeepEqual(base, v) {
+		return nil
+	}
+	return v
+}
+
+// mergeKey returns key when every item of the lists is a map with the key, or an empty string when the lists are
+// replaced as a whole.
+func mergeKey(key string, lists ...[]interface{}) string {
+	if key == "" {
+		return ""
+	}
+	for _, l := range lists {
+		for _, item := range l {
+			if m, ok := item.(document); !ok || m[key] == nil {
+				return ""
+			}
+		}
+	}
+	return key
+}
+
+// joinPath returns the path of the field name in the map at path. The items of the lists share the path of the list.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func find(l []interface{}, key string, value interface{}) document {
+	for _, item := range l {
+		if item.(document)[key] == value {
+			return item.(document)
+		}
+	}
+	return nil
+}
+
+func (l Layout) add(p string, v interface{}) error {
+	b := bytes.Buffer{}
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	l.Files[p] = b.Bytes()
+	return nil
+}
+
+// Write writes the layout into `<dir>/<layout name>`.
+func (l Layout) Write(dir string) error {
+	paths := make([]string, 0, len(l.Files))
+	for p := range l.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		file := filepath.Join(dir, l.Name, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, l.Files[p], 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package kustomize
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	"github.com/gciavarrini/cf-application-discovery/pkg/generate/kubernetes"
+	"github.com/gciavarrini/cf-application-discovery/pkg/output"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+// build renders the overlay of the layout like `kustomize build` does, applying its patches to the base resources
+// with the subset of the strategic merge rules used by the generator, and returns the resources by file name.
+func build(l Layout, overlay string) map[string]document {
+	dir := path.Join(overlaysDir, overlay)
+	k := kustomization{}
+	Expect(yaml.Unmarshal(l.Files[path.Join(dir, kustomizationFile)], &k)).To(Succeed())
+	base := kustomization{}
+	Expect(yaml.Unmarshal(l.Files[path.Join(baseDir, kustomizationFile)], &base)).To(Succeed())
+
+	docs := map[string]document{}
+	for _, r := range base.Resources {
+		docs[r] = read(l, path.Join(baseDir, r))
+	}
+	for _, r := range k.Resources[1:] {
+		docs[r] = read(l, path.Join(dir, r))
+	}
+	for _, p := range k.Patches {
+		Expect(docs).To(HaveKey(p.Path))
+		docs[p.Path] = merge(docs[p.Path], read(l, path.Join(dir, p.Path)), mergeKeys[docs[p.Path]["kind"].(string)], "").(document)
+	}
+	for _, doc := range docs {
+		doc["metadata"].(document)["namespace"] = k.Namespace
+	}
+	return docs
+}
+
+func read(l Layout, p string) document {
+	Expect(l.Files).To(HaveKey(p))
+	doc := document{}
+	Expect(yaml.Unmarshal(l.Files[p], &doc)).To(Succeed())
+	return doc
+}
+
+func merge(base, patch interface{}, keys map[string]string, p string) interface{} {
+	switch patch := patch.(type) {
+	case document:
+		b, ok := base.(document)
+		if !ok {
+			return patch
+		}
+		for k, v := range patch {
+			b[k] = merge(b[k], v, keys, joinPath(p, k))
+		}
+		return b
+	case []interface{}:
+		b, ok := base.([]interface{})
+		key := keys[p]
+		if !ok || key == "" {
+			return patch
+		}
+		for _, item := range patch {
+			if i := slices.IndexFunc(b, func(v interface{}) bool { return v.(document)[key] == item.(document)[key] }); i >= 0 {
+				b[i] = merge(b[i], item, keys, p)
+			} else {
+				b = append(b, item)
+			}
+		}
+		return b
+	}
+	return patch
+}
+
+// generated returns the resources generated for the application by the Kubernetes generator, by file name, with the
+// environment variables sorted by name like the generator does.
+func generated(app discover.Application) map[string]document {
+	k, err := kubernetes.Generate(app, kubernetes.Options{})
+	Expect(err).NotTo(HaveOccurred())
+	b := bytes.Buffer{}
+	Expect(output.Encode(&b, output.YAMLFormat, k.Objects)).To(Succeed())
+	docs := map[string]document{}
+	dec := yaml.NewDecoder(&b)
+	for _, o := range k.Objects {
+		doc := document{}
+		Expect(dec.Decode(&doc)).To(Succeed())
+		docs[fileName(o)] = doc
+	}
+	Expect(dec.Decode(&document{})).To(MatchError(io.EOF))
+	return docs
+}
+
+// sortEnv sorts the environment variables of the Deployments, which the patches append to the ones of the base.
+func sortEnv(docs map[string]document) map[string]document {
+	for _, doc := range docs {
+		if doc["kind"] != "Deployment" {
+			continue
+		}
+		spec := doc["spec"].(document)["template"].(document)["spec"].(document)
+		for _, c := range spec["containers"].([]interface{}) {
+			if env, ok := c.(document)["env"].([]interface{}); ok {
+				slices.SortFunc(env, func(a, b interface{}) int {
+					return strings.Compare(a.(document)["name"].(string), b.(document)["name"].(string))
+				})
+			}
+		}
+	}
+	return docs
+}
+
+// variants returns the dev, stage and prod variants of the same application.
+func variants() []discover.Application {
+	app := func(space string, instances int, memory discover.Quantity, env map[string]string, routes ...string) discover.Application {
+		a := discover.Application{
+			Metadata:  discover.Metadata{Name: "orders", Space: space},
+			Env:       env,
+			Docker:    discover.Docker{Image: "quay.io/team-a/orders:1.0"},
+			Routes:    discover.RouteSpec{NoRoute: len(routes) == 0},
+			Processes: discover.Processes{{Type: discover.Web, Command: "./orders", Memory: memory, Instances: instances}},
+		}
+		for _, r := range routes {
+			a.Routes.Routes = append(a.Routes.Routes, discover.Route{Route: r})
+		}
+		return a
+	}
+	return []discover.Application{
+		app("dev", 1, "512M", map[string]string{"LOG_LEVEL": "debug", "DB_HOST": "db.dev"}),
+		app("stage", 2, "512M", map[string]string{"LOG_LEVEL": "info", "DB_HOST": "db.stage"}, "orders.stage.example.com"),
+		app("prod", 5, "2G", map[string]string{"LOG_LEVEL": "info", "DB_HOST": "db.prod", "CACHE": "on"}, "orders.example.com", "shop.example.com/orders"),
+	}
+}
+
+var _ = Describe("Kustomize generator", func() {
+	It("generates a base with the shared fields and an overlay per space with the differences", func() {
+		r, err := Generate(variants(), Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Warnings).To(BeEmpty())
+		Expect(r.Layouts).To(HaveLen(1))
+		l := r.Layouts[0]
+		Expect(l.Name).To(Equal("orders"))
+
+		files := []string{}
+		for f := range l.Files {
+			files = append(files, f)
+		}
+		slices.Sort(files)
+		Expect(files).To(Equal([]string{
+			"base/deployment-orders.yaml",
+			"base/kustomization.yaml",
+			"overlays/dev/deployment-orders.yaml",
+			"overlays/dev/kustomization.yaml",
+			"overlays/prod/deployment-orders.yaml",
+			"overlays/prod/ingress-orders.yaml",
+			"overlays/prod/kustomization.yaml",
+			"overlays/prod/service-orders.yaml",
+			"overlays/stage/deployment-orders.yaml",
+			"overlays/stage/ingress-orders.yaml",
+			"overlays/stage/kustomization.yaml",
+			"overlays/stage/service-orders.yaml",
+		}))
+
+		Expect(string(l.Files["base/deployment-orders.yaml"])).To(Equal(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    app.kubernetes.io/component: web
+    app.kubernetes.io/name: orders
+  name: orders
+spec:
+  selector:
+    matchLabels:
+      app.kubernetes.io/component: web
+      app.kubernetes.io/name: orders
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/component: web
+        app.kubernetes.io/name: orders
+    spec:
+      containers:
+        - command:
+            - /bin/sh
+            - -c
+            - ./orders
+          env:
+            - name: PORT
+              value: "8080"
+          image: quay.io/team-a/orders:1.0
+          name: web
+          ports:
+            - containerPort: 8080
+              name: http
+              protocol: TCP
+          resources:
+            limits:
+              ephemeral-storage: 1Gi
+            requests:
+              ephemeral-storage: 1Gi
+`))
+		Expect(string(l.Files["overlays/prod/kustomization.yaml"])).To(Equal(`apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+namespace: prod
+resources:
+  - ../../base
+  - service-orders.yaml
+  - ingress-orders.yaml
+patches:
+  - path: deployment-orders.yaml
+`))
+		Expect(string(l.Files["overlays/prod/deployment-orders.yaml"])).To(Equal(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: orders
+spec:
+  replicas: 5
+  template:
+    spec:
+      containers:
+        - env:
+            - name: CACHE
+              value: "on"
+            - name: DB_HOST
+              value: db.prod
+            - name: LOG_LEVEL
+              value: info
+          name: web
+          resources:
+            limits:
+              memory: 2Gi
+            requests:
+              cpu: 250m
+              memory: 2Gi
+`))
+	})
+
+	It("reproduces the resources of each space when the overlays are built", func() {
+		apps := variants()
+		r, err := Generate(apps, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		for _, app := range apps {
+			Expect(sortEnv(build(r.Layouts[0], app.Metadata.Space))).To(Equal(sortEnv(generated(app))), app.Metadata.Space)
+		}
+	})
+
+	It("keeps the lists without merge key that differ between spaces whole in the overlays", func() {
+		apps := variants()[1:]
+		r, err := Generate(apps, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		l := r.Layouts[0]
+		Expect(l.Files).To(HaveKey("base/ingress-orders.yaml"))
+		Expect(string(l.Files["base/ingress-orders.yaml"])).NotTo(ContainSubstring("rules:"))
+		Expect(string(l.Files["overlays/prod/ingress-orders.yaml"])).To(Equal(`apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: orders
+spec:
+  rules:
+    - host: orders.example.com
+      http:
+        paths:
+          - backend:
+              service:
+                name: orders
+                port:
+                  name: http
+            path: /
+            pathType: Prefix
+    - host: shop.example.com
+      http:
+        paths:
+          - backend:
+              service:
+                name: orders
+                port:
+                  name: http
+            path: /orders
+            pathType: Prefix
+`))
+		for _, app := range apps {
+			Expect(sortEnv(build(l, app.Metadata.Space))).To(Equal(sortEnv(generated(app))), app.Metadata.Space)
+		}
+	})
+
+	It("uses the namespace of the options in every overlay", func() {
+		r, err := Generate(variants(), Options{Kubernetes: kubernetes.Options{Namespace: "orders"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(r.Layouts[0].Files["overlays/dev/kustomization.yaml"])).To(ContainSubstring("namespace: orders\n"))
+	})
+
+	It("generates a layout per application and reports each warning once", func() {
+		apps := variants()
+		for i := range apps {
+			apps[i].Docker = discover.Docker{}
+		}
+		apps = append(apps, discover.Application{Metadata: discover.Metadata{Name: "reports", Space: "prod"}, Routes: discover.RouteSpec{NoRoute: true}, Docker: discover.Docker{Image: "reports:1"}})
+		r, err := Generate(apps, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Layouts).To(HaveLen(2))
+		Expect(r.Layouts[1].Name).To(Equal("reports"))
+		Expect(r.Layouts[1].Files).To(HaveKey("overlays/prod/kustomization.yaml"))
+		Expect(r.Layouts[1].Files).NotTo(HaveKey("overlays/prod/deployment-reports.yaml"))
+		Expect(r.Warnings).To(Equal([]string{"no container image is available for application orders: build orders:latest from its source code"}))
+	})
+
+	It("fails when an application has no space", func() {
+		_, err := Generate([]discover.Application{{Metadata: discover.Metadata{Name: "orders"}}}, Options{})
+		Expect(err).To(MatchError("application orders has no space: the space is required to name its overlay"))
+	})
+
+	It("fails when an application is defined twice in the same space", func() {
+		apps := variants()
+		_, err := Generate(append(apps, apps[0]), Options{})
+		Expect(err).To(MatchError("application orders is defined more than once in space dev"))
+	})
+})
+
+var _ = Describe("Resources intersection", func() {
+	It("keeps the fields that are equal and the list items with the same key", func() {
+		a := document{"replicas": 1, "labels": document{"a": "1", "b": "2"}, "env": []interface{}{
+			document{"name": "A", "value": "1"}, document{"name": "B", "value": "2"},
+		}, "args": []interface{}{"x"}}
+		b := document{"replicas": 2, "labels": document{"a": "1"}, "env": []interface{}{
+			document{"name": "B", "value": "2"}, document{"name": "A", "value": "3"},
+		}, "args": []interface{}{"y"}}
+		keys := map[string]string{"env": "name"}
+		Expect(intersect(a, b, keys, "")).To(Equal(document{"labels": document{"a": "1"}, "env": []interface{}{document{"name": "B", "value": "2"}}}))
+		Expect(diff(intersect(a, b, keys, ""), b, keys, "")).To(Equal(document{"replicas": 2, "env": []interface{}{document{"name": "A", "value": "3"}}, "args": []interface{}{"y"}}))
+		Expect(diff(a, a, keys, "")).To(BeNil())
+	})
+
+	It("merges the ports of the Services by port", func() {
+		keys := mergeKeys["Service"]
+		a := document{"spec": document{"ports": []interface{}{document{"name": "http", "port": 80, "targetPort": "http"}}}}
+		b := document{"spec": document{"ports": []interface{}{document{"name": "http", "port": 8080, "targetPort": "http"}}}}
+		Expect(intersect(a, b, keys, "")).To(BeNil())
+		Expect(diff(document{}, b, keys, "")).To(Equal(b))
+	})
+
+	It("replaces the lists of the custom resources as a whole", func() {
+		a := document{"kind": "ServiceBinding", "spec": document{"env": []interface{}{
+			document{"name": "DB_USER", "key": "username"}, document{"name": "DB_URL", "key": "uri"},
+		}}}
+		b := document{"kind": "ServiceBinding", "spec": document{"env": []interface{}{
+			document{"name": "DB_USER", "key": "username"}, document{"name": "DB_URL", "key": "jdbcUrl"},
+		}}}
+		shared := intersect(a, b, mergeKeys["ServiceBinding"], "")
+		Expect(shared).To(Equal(document{"kind": "ServiceBinding"}))
+		Expect(diff(shared, b, mergeKeys["ServiceBinding"], "")).To(Equal(document{"spec": b["spec"]}))
+	})
+})
@@ -0,0 +1,122 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+)
+
+const (
+	// IndexFileName is the base name of the index file written at the root of the output directory.
+	IndexFileName = "index"
+	// defaultSpaceDir is the directory used for applications that are not assigned to any space.
+	defaultSpaceDir = "default"
+	// defaultAppFileName is used when the application name contains no usable characters.
+	defaultAppFileName = "application"
+)
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Record associates a discovered application with the source it was discovered from.
+type Record struct {
+	Application discover.Application
+	// Source identifies where the application was discovered, such as the path to its manifest.
+	Source string
+}
+
+// Index lists the applications written to the output directory.
+type Index struct {
+	Applications []IndexEntry `yaml:"applications"`
+}
+
+// IndexEntry describes a single application file in the output directory.
+type IndexEntry struct {
+	// Name is the name of the application.
+	Name string `yaml:"name"`
+	// Space is the space of the application. Empty when the application is not assigned to any space.
+	Space string `yaml:"space,omitempty"`
+	// Organization is the organization of the space of the application, when known.
+	Organization string `yaml:"organization,omitempty"`
+	// File is the path of the application file relative to the output directory.
+	File string `yaml:"file"`
+	// Source identifies where the application was discovered.
+	Source string `yaml:"source,omitempty"`
+}
+
+// WriteDirectory writes each application to `<dir>/<space>/<app-name>.<format>`, or to
+// `<dir>/<organization>/<space>/<app-name>.<format>` when the organization is known, and an index file
+// listing all of them at the root of dir. Names are sanitized so that they are safe to use as file
+// names, and applications that map to the same file get a numeric suffix.
+func WriteDirectory(dir string, format Format, records []Record) (Index, error) {
+	index := Index{Applications: []IndexEntry{}}
+	used := map[string]bool{}
+	for _, r := range records {
+		file := uniqueFileName(used, filepath.Join(spaceDirName(r.Application.Metadata), safeFileName(r.Application.Metadata.Name)), string(format))
+		if err := writeFile(filepath.Join(dir, file), format, r.Application); err != nil {
+			return Index{}, err
+		}
+		index.Applications = append(index.Applications, IndexEntry{
+			Name:         r.Application.Metadata.Name,
+			Space:        r.Application.Metadata.Space,
+			Organization: r.Application.Metadata.Organization,
+			File:         filepath.ToSlash(file),
+			Source:       r.Source,
+		})
+	}
+	if err := writeFile(filepath.Join(dir, IndexFileName+"."+string(format)), format, index); err != nil {
+		return Index{}, err
+	}
+	return index, nil
+}
+
+func writeFile(path string, format Format, doc interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := EncodeDocument(f, format, doc); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// safeFileName replaces the characters that are not safe in a file name with dashes.
+func safeFileName(name string) string {
+	s := strings.Trim(unsafeFileNameChars.ReplaceAllString(name, "-"), ".-")
+	if s == "" {
+		return defaultAppFileName
+	}
+	return s
+}
+
+func spaceDirName(m discover.Metadata) string {
+	space := defaultSpaceDir
+	if m.Space != "" {
+		space = safeFileName(m.Space)
+	}
+	if m.Organization == "" {
+		return space
+	}
+	return filepath.Join(safeFileName(m.Organization), space)
+}
+
+// uniqueFileName returns the file name for base with the given extension, adding a numeric suffix
+// when the name has already been used. The comparison is case insensitive so that the output is
+// portable to case insensitive file systems.
+func uniqueFileName(used map[string]bool, base, ext string) string {
+	name := base + "." + ext
+	for i := 2; used[strings.ToLower(name)]; i++ {
+		name = base + "-" + strconv.Itoa(i) + "." + ext
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
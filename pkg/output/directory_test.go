@@ -0,0 +1,61 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Write directory", func() {
+	When("sanitizing file names", func() {
+		DescribeTable("validate the resulting name", func(name, expected string) {
+			Expect(safeFileName(name)).To(Equal(expected))
+		},
+			Entry("with a safe name", "my-app_1.v2", "my-app_1.v2"),
+			Entry("with path separators", "../../etc/passwd", "etc-passwd"),
+			Entry("with spaces and symbols", "my app (prod)", "my-app-prod"),
+			Entry("with leading dots", ".hidden", "hidden"),
+			Entry("with no usable characters", "***", "application"),
+			Entry("with an empty name", "", "application"),
+		)
+	})
+
+	When("resolving name collisions", func() {
+		It("adds a numeric suffix regardless of the case", func() {
+			used := map[string]bool{}
+			Expect(uniqueFileName(used, "dev/app", "yaml")).To(Equal("dev/app.yaml"))
+			Expect(uniqueFileName(used, "dev/App", "yaml")).To(Equal("dev/App-2.yaml"))
+			Expect(uniqueFileName(used, "dev/app", "yaml")).To(Equal("dev/app-3.yaml"))
+			Expect(uniqueFileName(used, "prod/app", "yaml")).To(Equal("prod/app.yaml"))
+		})
+	})
+
+	When("writing the applications", func() {
+		It("writes one file per application and the index", func() {
+			dir := GinkgoT().TempDir()
+			records := []Record{
+				{Application: discover.Application{Metadata: discover.Metadata{Name: "foo", Space: "dev"}}, Source: "manifest.yml"},
+				{Application: discover.Application{Metadata: discover.Metadata{Name: "foo", Space: "dev"}}, Source: "other.yml"},
+				{Application: discover.Application{Metadata: discover.Metadata{Name: "bar/baz"}}, Source: "manifest.yml"},
+				{Application: discover.Application{Metadata: discover.Metadata{Name: "foo", Space: "dev", Organization: "my-org"}}, Source: "https://api.example.com"},
+			}
+			index, err := WriteDirectory(dir, YAMLFormat, records)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index.Applications).To(Equal([]IndexEntry{
+				{Name: "foo", Space: "dev", File: "dev/foo.yaml", Source: "manifest.yml"},
+				{Name: "foo", Space: "dev", File: "dev/foo-2.yaml", Source: "other.yml"},
+				{Name: "bar/baz", File: "default/bar-baz.yaml", Source: "manifest.yml"},
+				{Name: "foo", Space: "dev", Organization: "my-org", File: "my-org/dev/foo.yaml", Source: "https://api.example.com"},
+			}))
+			for _, f := range []string{"dev/foo.yaml", "dev/foo-2.yaml", "default/bar-baz.yaml", "my-org/dev/foo.yaml", "index.yaml"} {
+				Expect(filepath.Join(dir, f)).To(BeAnExistingFile())
+			}
+			b, err := os.ReadFile(filepath.Join(dir, "dev", "foo.yaml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).To(ContainSubstring("name: foo\nspace: dev\n"))
+		})
+	})
+})
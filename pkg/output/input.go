@@ -0,0 +1,99 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	"gopkg.in/yaml.v3"
+)
+
+// DecodeApplications reads the applications rendered by Encode from r, in any of the supported formats. Each YAML
+// document can contain a single application or a list of them, like the JSON arrays.
+func DecodeApplications(r io.Reader) ([]discover.Application, error) {
+	apps := []discover.Application{}
+	dec := yaml.NewDecoder(r)
+	for {
+		n := yaml.Node{}
+		err := dec.Decode(&n)
+		if errors.Is(err, io.EOF) {
+			return apps, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		doc := &n
+		if n.Kind == yaml.DocumentNode && len(n.Content) == 1 {
+			doc = n.Content[0]
+		}
+		switch doc.Kind {
+		case yaml.SequenceNode:
+			list := []discover.Application{}
+			if err := doc.Decode(&list); err != nil {
+				return nil, err
+			}
+			apps = append(apps, list...)
+		case yaml.MappingNode:
+			app := discover.Application{}
+			if err := doc.Decode(&app); err != nil {
+				return nil, err
+			}
+			apps = append(apps, app)
+		default:
+			return nil, fmt.Errorf("line %d: expected an application or a list of applications", doc.Line)
+		}
+	}
+}
+
+// ReadApplications reads the applications in the file at path or, when path is a directory like the ones written by
+// WriteDirectory, in all the YAML and JSON files it contains except the index. A path of `-` reads from stdin.
+func ReadApplications(path string) ([]discover.Application, error) {
+	if path == "-" {
+		return DecodeApplications(os.Stdin)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return readApplicationFile(path)
+	}
+	apps := []discover.Application{}
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := filepath.Ext(p)
+		if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			return nil
+		}
+		if filepath.Dir(p) == filepath.Clean(path) && strings.TrimSuffix(d.Name(), ext) == IndexFileName {
+			return nil
+		}
+		found, err := readApplicationFile(p)
+		apps = append(apps, found...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+func readApplicationFile(path string) ([]discover.Application, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	apps, err := DecodeApplications(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the applications in %s: %w", path, err)
+	}
+	return apps, nil
+}
@@ -0,0 +1,41 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+
+	discover "github.com/gciavarrini/cf-application-discovery/pkg/discover/cloud_foundry"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Read applications", func() {
+	apps := []discover.Application{
+		{Metadata: discover.Metadata{Name: "foo", Space: "dev", Version: "1"}, Env: map[string]string{"A": "1"}, Instances: 2, Timeout: 60},
+		{Metadata: discover.Metadata{Name: "bar", Version: "1"}, Routes: discover.RouteSpec{NoRoute: true}, Instances: 1, Timeout: 60},
+	}
+
+	DescribeTable("decodes the applications rendered in each format", func(format Format) {
+		b := bytes.Buffer{}
+		Expect(Encode(&b, format, apps)).To(Succeed())
+		Expect(DecodeApplications(&b)).To(Equal(apps))
+	},
+		Entry("as YAML documents", YAMLFormat),
+		Entry("as a JSON array", JSONFormat),
+	)
+
+	It("rejects documents that are not applications", func() {
+		_, err := DecodeApplications(strings.NewReader("---\nfoo\n"))
+		Expect(err).To(MatchError("line 2: expected an application or a list of applications"))
+	})
+
+	It("reads back the directories written by WriteDirectory", func() {
+		dir := GinkgoT().TempDir()
+		_, err := WriteDirectory(dir, JSONFormat, []Record{{Application: apps[0]}, {Application: apps[1]}})
+		Expect(err).NotTo(HaveOccurred())
+		read, err := ReadApplications(dir)
+		Expect(err).NotTo(HaveOccurred())
+		// The files are read in lexical order: default/bar.json and then dev/foo.json.
+		Expect(read).To(Equal([]discover.Application{apps[1], apps[0]}))
+	})
+})
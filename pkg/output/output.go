@@ -0,0 +1,88 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format represents the serialization format used to render the discovered applications.
+type Format string
+
+const (
+	YAMLFormat Format = "yaml"
+	JSONFormat Format = "json"
+)
+
+// ParseFormat validates the given format name and returns its Format value.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case YAMLFormat, JSONFormat:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported output format %q: valid values are %s and %s", name, YAMLFormat, JSONFormat)
+}
+
+// Encode serializes the documents in the given format into w. YAML documents are
+// separated with `---` and JSON documents are rendered as a single array.
+func Encode[T any](w io.Writer, format Format, docs []T) error {
+	switch format {
+	case JSONFormat:
+		out := make([]interface{}, 0, len(docs))
+		for _, d := range docs {
+			v, err := toJSONCompatible(d)
+			if err != nil {
+				return err
+			}
+			out = append(out, v)
+		}
+		return encodeJSON(w, out)
+	case YAMLFormat:
+		if len(docs) == 0 {
+			return nil
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		for _, d := range docs {
+			if err := enc.Encode(d); err != nil {
+				return err
+			}
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+// EncodeDocument serializes a single document in the given format into w.
+func EncodeDocument(w io.Writer, format Format, doc interface{}) error {
+	if format == JSONFormat {
+		v, err := toJSONCompatible(doc)
+		if err != nil {
+			return err
+		}
+		return encodeJSON(w, v)
+	}
+	return Encode(w, format, []interface{}{doc})
+}
+
+func encodeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// toJSONCompatible round trips the document through YAML so that the JSON output
+// honours the `yaml` field names and inlined structures of the discovery model.
+func toJSONCompatible(doc interface{}) (interface{}, error) {
+	b, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
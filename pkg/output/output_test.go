@@ -0,0 +1,46 @@
+package output
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type sample struct {
+	Name   string            `yaml:"name"`
+	Inline map[string]string `yaml:",inline"`
+}
+
+var _ = Describe("Encode", func() {
+	When("parsing the output format", func() {
+		DescribeTable("validate the accepted values", func(name string, expected Format, expectErr bool) {
+			f, err := ParseFormat(name)
+			if expectErr {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f).To(Equal(expected))
+		},
+			Entry("yaml", "yaml", YAMLFormat, false),
+			Entry("json", "json", JSONFormat, false),
+			Entry("unknown format", "xml", Format(""), true),
+			Entry("empty format", "", Format(""), true),
+		)
+	})
+
+	When("encoding documents", func() {
+		docs := []sample{{Name: "foo", Inline: map[string]string{"space": "dev"}}, {Name: "bar"}}
+		DescribeTable("validate the rendered output", func(format Format, docs []sample, expected string) {
+			b := bytes.Buffer{}
+			Expect(Encode(&b, format, docs)).To(Succeed())
+			Expect(b.String()).To(Equal(expected))
+		},
+			Entry("as YAML documents", YAMLFormat, docs, "name: foo\nspace: dev\n---\nname: bar\n"),
+			Entry("as a JSON array", JSONFormat, docs, "[\n  {\n    \"name\": \"foo\",\n    \"space\": \"dev\"\n  },\n  {\n    \"name\": \"bar\"\n  }\n]\n"),
+			Entry("as an empty JSON array", JSONFormat, nil, "[]\n"),
+			Entry("as empty YAML", YAMLFormat, nil, ""),
+		)
+	})
+})